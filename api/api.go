@@ -3,12 +3,9 @@ package api
 import (
 	"context"
 	"egg/datastore"
-	"encoding/base64"
+	"egg/format"
 	"fmt"
-	"io"
 	"math"
-	"net/http"
-	"net/url"
 	"sort"
 	"time"
 
@@ -16,60 +13,12 @@ import (
 
 	"gorm.io/gorm"
 
-	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 )
 
-var (
-	deviceId        = "IOS"
-	clientVersion   = uint32(37)
-	apiFirstContact = "https://www.auxbrain.com/ei/first_contact"
-)
-
-// GetBackupFromAPI queries the Egg, Inc. API for a user's backup info
-func GetBackupFromAPI(eiUID string) (*FirstContact_Payload, error) {
-	responseBody := new(FirstContact)
-	authenticatedMsg := new(AuthenticatedMessage)
-	payload := FirstContactRequestPayload{
-		EiUserId:      eiUID,
-		DeviceId:      deviceId,
-		ClientVersion: clientVersion,
-	}
-
-	reqBin, err := proto.Marshal(proto.Message(&payload))
-	if err != nil {
-		return &FirstContact_Payload{}, err
-	}
-
-	reqDataEncoded := base64.StdEncoding.EncodeToString(reqBin)
-
-	resp, err := http.PostForm(apiFirstContact, url.Values{"data": {reqDataEncoded}})
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-	if err != nil {
-		return &FirstContact_Payload{}, err
-	}
-
-	body, err := io.ReadAll(resp.Body)
-
-	enc := base64.StdEncoding
-	buf := make([]byte, enc.DecodedLen(len(body)))
-	decoded, err := enc.Decode(buf, body)
-
-	if err = proto.Unmarshal(buf[:decoded], authenticatedMsg); err != nil {
-		return &FirstContact_Payload{}, err
-	}
-
-	if err = proto.Unmarshal(authenticatedMsg.Message, responseBody); err != nil {
-		return &FirstContact_Payload{}, err
-	}
-
-	return responseBody.Data, nil
-}
-
-// AddUserToDatabase builds a datastore.User object and adds it to a datastore
-func AddUserToDatabase(ctx context.Context, store datastore.Database, backup *FirstContact_Payload, discordName string) (datastore.User, error) {
+// AddUserToDatabase builds a datastore.User object, adds it to a datastore and records a snapshot of its progress.
+// When a guild ID is provided the user is also registered in that guild.
+func AddUserToDatabase(ctx context.Context, store datastore.Database, backup *FirstContact_Payload, guildID, discordID, discordName string) (datastore.User, error) {
 	var soulFood int32
 	var prophecyBonus int32
 	for _, research := range backup.GetProgress().GetEpicResearches() {
@@ -82,12 +31,21 @@ func AddUserToDatabase(ctx context.Context, store datastore.Database, backup *Fi
 	}
 	user := datastore.User{
 		EggIncID:        backup.EiUserId,
+		DiscordID:       discordID,
 		DiscordName:     discordName,
 		GameAccountName: backup.UserName,
 		SoulFood:        soulFood,
 		ProphecyBonus:   prophecyBonus,
 		SoulEggs:        backup.GetProgress().GetSoulEggs(),
 		ProphecyEggs:    backup.GetProgress().GetProphecyEggs(),
+
+		GoldenEggs:          int64(backup.GetProgress().GetLifetimeGoldenEggs()),
+		Prestiges:           backup.GetStats().GetPrestiges(),
+		DroneTakedowns:      backup.GetStats().GetDroneTakedowns(),
+		EliteDroneTakedowns: backup.GetStats().GetEliteDroneTakedowns(),
+		BoostsUsed:          backup.GetStats().GetBoostsUsed(),
+		PiggyLevel:          backup.GetStats().GetPiggyLevel(),
+		TrophyLevels:        trophyLevels(backup.GetProgress().GetFarmTrophyLevel()),
 	}
 
 	tx, err := store.Transaction(ctx)
@@ -107,11 +65,35 @@ func AddUserToDatabase(ctx context.Context, store datastore.Database, backup *Fi
 		return datastore.User{}, err
 	}
 
+	if _, err = tx.CreateUserSnapshot(record.Snapshot(time.Now())); err != nil {
+		return datastore.User{}, err
+	}
+
+	if err = tx.SaveMissions(MissionRecords(backup, time.Now())); err != nil {
+		return datastore.User{}, err
+	}
+
+	if guildID != "" {
+		if err = tx.AddUserToGuild(guildID, record.EggIncID); err != nil {
+			return datastore.User{}, err
+		}
+	}
+
 	return record, nil
 }
 
-// RemoveUserFromDatabase removes a user from the database provided the provided ID and discord username match up with the database record
-func RemoveUserFromDatabase(ctx context.Context, store datastore.Database, eggID, discordName string) error {
+func trophyLevels(trophies []TrophyType) string {
+	levels := make([]int32, 0, len(trophies))
+	for _, trophy := range trophies {
+		levels = append(levels, int32(trophy))
+	}
+	return datastore.JoinTrophyLevels(levels)
+}
+
+// RemoveUserFromDatabase removes a user from a guild provided the provided ID and Discord user ID match up with the database record.
+// The user is deleted outright once they're no longer registered in any guild, or immediately when no guild ID is provided.
+// Records that predate Discord user IDs and haven't been backfilled yet fall back to matching on username.
+func RemoveUserFromDatabase(ctx context.Context, store datastore.Database, guildID, eggID, discordID, discordName string) error {
 	tx, err := store.Transaction(ctx)
 	if err != nil {
 		return err
@@ -137,22 +119,42 @@ func RemoveUserFromDatabase(ctx context.Context, store datastore.Database, eggID
 		return err
 	}
 
-	if check.DiscordName != discordName {
+	owner := check.DiscordID == discordID
+	if check.DiscordID == "" {
+		owner = check.DiscordName == discordName
+	}
+	if !owner {
 		return errors.New("Your Discord user is not associated with the ID you provided")
 	}
 
+	if guildID != "" {
+		if err = tx.RemoveUserFromGuild(guildID, eggID); err != nil {
+			return err
+		}
+
+		var remaining []string
+		if remaining, err = tx.GetUserGuildIDs(eggID); err != nil || len(remaining) > 0 {
+			return err
+		}
+	}
+
 	err = tx.DeleteUser(datastore.User{
-		EggIncID:    eggID,
-		DiscordName: discordName,
+		EggIncID:  eggID,
+		DiscordID: discordID,
 	})
 
 	return err
 }
 
-func BuildSELeaderboard(ctx context.Context, store datastore.Database) (*discordgo.MessageEmbed, error) {
+// ErrNotRegistered is returned when a Discord user hasn't registered any Egg, Inc. accounts
+var ErrNotRegistered = errors.New("You haven't registered an Egg, Inc. user ID yet. Use /register first")
+
+// GetDiscordUsers returns every account a Discord user has registered, including any from before
+// registrations were tied to a Discord user ID that still only carry their username
+func GetDiscordUsers(ctx context.Context, store datastore.Database, discordID, discordName string) (datastore.Users, error) {
 	tx, err := store.Transaction(ctx)
 	if err != nil {
-		return &discordgo.MessageEmbed{}, err
+		return nil, err
 	}
 	defer func() {
 		if err == nil {
@@ -162,43 +164,180 @@ func BuildSELeaderboard(ctx context.Context, store datastore.Database) (*discord
 		}
 	}()
 
-	records, err := tx.GetUsers()
+	// finding nobody is covered by the check below, but anything else is a real failure
+	users, err := tx.GetUsersByDiscordID(discordID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	legacy, err := tx.GetUsersWithoutDiscordID()
 	if err != nil {
-		return &discordgo.MessageEmbed{}, err
+		return nil, err
+	}
+	for _, user := range legacy {
+		if user.DiscordName == discordName {
+			users = append(users, user)
+		}
+	}
+
+	if len(users) == 0 {
+		err = ErrNotRegistered
+		return nil, err
 	}
 
-	sort.Slice(records, func(i, j int) bool {
-		return records[i].SoulEggs > records[j].SoulEggs
+	return users, nil
+}
+
+// NameResolver returns the name a user should be shown as, e.g. their current nickname in a guild
+type NameResolver func(user datastore.User) string
+
+// resolve falls back to the username recorded at registration when there's no resolver or it comes up empty
+func (r NameResolver) resolve(user datastore.User) string {
+	if r != nil {
+		if name := r(user); name != "" {
+			return name
+		}
+	}
+	return user.DiscordName
+}
+
+// LeaderboardPageSize is how many users are shown on each page of a leaderboard, well inside Discord's
+// limits of 25 fields and 6000 characters per embed
+const LeaderboardPageSize = 10
+
+// LeaderboardQuery picks which page of a guild's leaderboard to build and for whom
+type LeaderboardQuery struct {
+	GuildID string
+	// Metric is one of MetricNames, DefaultMetric when empty
+	Metric string
+	// Page is numbered from zero; pages past either end show the first or last page
+	Page int
+	// Highlight is the Discord user ID whose accounts are called out on the board, if any
+	Highlight string
+	// HighlightPage shows the page with Highlight's best placed account instead of Page
+	HighlightPage bool
+	// Numbers is how the reader likes numbers written
+	Numbers format.Options
+
+	RefreshInterval time.Duration
+	Names           NameResolver
+}
+
+// LeaderboardPage is one page of a leaderboard along with where it sits among the others
+type LeaderboardPage struct {
+	Embed  *discordgo.MessageEmbed
+	Metric string
+	Page   int
+	Pages  int
+}
+
+// BuildLeaderboard ranks every user registered in a guild by one of the Metrics, one page at a time
+func BuildLeaderboard(ctx context.Context, store datastore.Database, query LeaderboardQuery) (LeaderboardPage, error) {
+	if query.Metric == "" {
+		query.Metric = DefaultMetric
+	}
+	metric, ok := Metrics[query.Metric]
+	if !ok {
+		return LeaderboardPage{}, errors.New(fmt.Sprintf("'%s' isn't a leaderboard I know about", query.Metric))
+	}
+
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return LeaderboardPage{}, err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	records, err := tx.GetGuildUsers(query.GuildID)
+	if err != nil {
+		return LeaderboardPage{}, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return metric.Value(records[i]) > metric.Value(records[j])
 	})
 
-	embedFields := make([]*discordgo.MessageEmbedField, 0)
-	for i, record := range records {
-		_, humanEB, se, mathErr := GetEBAndSE(record)
-		if mathErr != nil {
-			return &discordgo.MessageEmbed{}, mathErr
+	// the highlighted user's best position, counting from zero
+	position := -1
+	if query.Highlight != "" {
+		for i, record := range records {
+			if record.DiscordID == query.Highlight {
+				position = i
+				break
+			}
+		}
+	}
+
+	pages := (len(records) + LeaderboardPageSize - 1) / LeaderboardPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	page := query.Page
+	if query.HighlightPage && position >= 0 {
+		page = position / LeaderboardPageSize
+	}
+	switch {
+	case page < 0:
+		page = 0
+	case page >= pages:
+		page = pages - 1
+	}
+
+	first := page * LeaderboardPageSize
+	last := first + LeaderboardPageSize
+	if last > len(records) {
+		last = len(records)
+	}
+
+	embedFields := make([]*discordgo.MessageEmbedField, 0, last-first)
+	for i := first; i < last; i++ {
+		record := records[i]
+
+		name := fmt.Sprintf("%d. %s %s", i+1, query.Names.resolve(record), metric.Headline(record, query.Numbers))
+		if query.Highlight != "" && record.DiscordID == query.Highlight {
+			name = ":point_right: " + name
+		}
+
+		detail := GetRank(record).Name
+		if metric.Detail != nil {
+			detail = metric.Detail(record, query.Numbers)
 		}
 
 		field := &discordgo.MessageEmbedField{
-			Name:   fmt.Sprintf("%d. %s %s", i+1, record.DiscordName, humanEB),
-			Value:  fmt.Sprintf("%s soul eggs", se),
+			Name:   name,
+			Value:  detail,
 			Inline: false,
 		}
 		embedFields = append(embedFields, field)
 	}
 
+	var description string
+	if position >= 0 {
+		description = fmt.Sprintf("You're #%d of %d", position+1, len(records))
+	}
+
 	embed := &discordgo.MessageEmbed{
-		Type:      discordgo.EmbedTypeRich,
-		Title:     "Soul Egg Leaderboard",
-		Timestamp: time.Now().Format(time.RFC3339),
-		Color:     0x8700C3, // button purple
+		Type:        discordgo.EmbedTypeRich,
+		Title:       metric.Title,
+		Description: description,
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Color:       0x8700C3, // button purple
 		// Color:     0x00ff00, // green
 		Footer: &discordgo.MessageEmbedFooter{
-			Text: fmt.Sprint("Updates every X minutes | Last updated"),
+			Text: fmt.Sprintf("Page %d of %d | Updates every %g minutes | Last updated", page+1, pages, query.RefreshInterval.Minutes()),
 		},
 		Fields: embedFields,
 	}
 
-	return embed, nil
+	return LeaderboardPage{Embed: embed, Metric: query.Metric, Page: page, Pages: pages}, nil
 }
 
 // GetEBAndSE returns a calculated Earnings bonus as well as a count of Soul Eggs, both in a human readable format
@@ -208,16 +347,19 @@ func GetEBAndSE(user datastore.User) (float64, string, string, error) {
 }
 
 func calculateEB(data datastore.User) (float64, string) {
-	sePercent := (0.1 + (float64(data.SoulFood) * .01)) * 100
-	pePercent := math.Pow(float64(1)+0.05+(float64(data.ProphecyBonus)*0.01), float64(data.ProphecyEggs)) * 100
-	bonus := (sePercent * pePercent) / 100
+	bonus := ebPerSoulEgg(data)
 
 	return bonus * data.SoulEggs, forPeople(bonus * data.SoulEggs)
 }
 
+// ebPerSoulEgg is how much earnings bonus, in percent, each soul egg is worth given a user's prophecy eggs and epic research
+func ebPerSoulEgg(data datastore.User) float64 {
+	sePercent := (0.1 + (float64(data.SoulFood) * .01)) * 100
+	pePercent := math.Pow(float64(1)+0.05+(float64(data.ProphecyBonus)*0.01), float64(data.ProphecyEggs)) * 100
+	return (sePercent * pePercent) / 100
+}
+
+// forPeople writes a number the default way, for places that don't know who'll be reading it
 func forPeople(bigAssNumber float64) string {
-	units := []string{"", "k", "m", "b", "T", "q", "Q", "s", "S", "o", "N", "d"}
-	k := float64(1000)
-	magnitude := math.Floor(math.Log(bigAssNumber) / math.Log(k))
-	return fmt.Sprintf("%.3f%s", bigAssNumber/(math.Pow(k, magnitude)), units[int(magnitude)])
+	return format.Number(bigAssNumber)
 }
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
 	"egg/datastore"
+	"egg/format"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -67,7 +72,8 @@ func TestCalculateEB(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			require.Equal(t, test.calculatedEB, calculateEB(test.user))
+			_, humanEB := calculateEB(test.user)
+			require.Equal(t, test.calculatedEB, humanEB)
 		})
 	}
 }
@@ -146,3 +152,197 @@ func TestForPeople(t *testing.T) {
 		})
 	}
 }
+
+func TestGetGrowth(t *testing.T) {
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+	store := datastore.Database{DB: db}
+
+	ctx := context.Background()
+	tx, err := store.Transaction(ctx)
+	require.NoError(t, err)
+
+	user, err := tx.CreateOrUpdateUser(datastore.User{EggIncID: "EI1111", DiscordName: "krohmag", SoulEggs: 2e18, ProphecyEggs: 2})
+	require.NoError(t, err)
+	require.NoError(t, tx.AddUserToGuild("guild", user.EggIncID))
+
+	started := user
+	started.SoulEggs = 1e18
+	started.ProphecyEggs = 1
+	_, err = tx.CreateUserSnapshot(started.Snapshot(time.Now().Add(-3 * 24 * time.Hour)))
+	require.NoError(t, err)
+	_, err = tx.CreateUserSnapshot(user.Snapshot(time.Now()))
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	t.Run("window includes the old snapshot", func(t *testing.T) {
+		growth, err := GetGrowth(ctx, store, "guild", time.Now().Add(-GrowthPeriods["week"]))
+		require.NoError(t, err)
+		require.Len(t, growth, 1)
+		require.Equal(t, 1e18, growth[0].SoulEggs)
+		require.Equal(t, int32(1), growth[0].ProphecyEggs)
+		require.InDelta(t, 110, growth[0].EBPercent, 0.001)
+	})
+
+	t.Run("window only includes the latest snapshot", func(t *testing.T) {
+		growth, err := GetGrowth(ctx, store, "guild", time.Now().Add(-GrowthPeriods["day"]))
+		require.NoError(t, err)
+		require.Len(t, growth, 1)
+		require.Zero(t, growth[0].EBPercent)
+	})
+
+	t.Run("snapshots are kept without a retention", func(t *testing.T) {
+		require.NoError(t, PruneSnapshots(ctx, store, time.Now().Add(365*24*time.Hour), 0))
+		growth, err := GetGrowth(ctx, store, "guild", time.Now().Add(-GrowthPeriods["week"]))
+		require.NoError(t, err)
+		require.Len(t, growth, 1)
+		require.NotZero(t, growth[0].EBPercent)
+	})
+
+	t.Run("old snapshots are pruned", func(t *testing.T) {
+		retention := 31 * 24 * time.Hour
+		require.NoError(t, PruneSnapshots(ctx, store, time.Now().Add(retention-24*time.Hour), retention))
+		growth, err := GetGrowth(ctx, store, "guild", time.Now().Add(-GrowthPeriods["week"]))
+		require.NoError(t, err)
+		require.Len(t, growth, 1)
+		require.Zero(t, growth[0].EBPercent)
+	})
+
+	t.Run("other guilds are left out", func(t *testing.T) {
+		growth, err := GetGrowth(ctx, store, "other-guild", time.Now().Add(-GrowthPeriods["week"]))
+		require.NoError(t, err)
+		require.Empty(t, growth)
+	})
+
+	t.Run("unknown period", func(t *testing.T) {
+		_, err := BuildGainersLeaderboard(ctx, store, "guild", "decade", nil, format.Default)
+		require.Error(t, err)
+	})
+}
+
+func TestBuildLeaderboard(t *testing.T) {
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+	store := datastore.Database{DB: db}
+
+	ctx := context.Background()
+	tx, err := store.Transaction(ctx)
+	require.NoError(t, err)
+	// 25 users with user n holding (26-n)e18 soul eggs, so that user n places nth
+	for n := 1; n <= 25; n++ {
+		user, createErr := tx.CreateOrUpdateUser(datastore.User{
+			EggIncID:        fmt.Sprintf("EI%04d", n),
+			DiscordID:       fmt.Sprintf("%d", n),
+			DiscordName:     fmt.Sprintf("farmer%d", n),
+			GameAccountName: fmt.Sprintf("farm%d", n),
+			SoulEggs:        float64(26-n) * 1e18,
+		})
+		require.NoError(t, createErr)
+		require.NoError(t, tx.AddUserToGuild("guild", user.EggIncID))
+	}
+	require.NoError(t, tx.Commit())
+
+	tests := []struct {
+		name        string
+		query       LeaderboardQuery
+		page        int
+		fields      int
+		firstField  string
+		description string
+	}{
+		{
+			name:       "first page",
+			query:      LeaderboardQuery{GuildID: "guild"},
+			page:       0,
+			fields:     LeaderboardPageSize,
+			firstField: "1. farmer1",
+		},
+		{
+			name:       "last page is partial",
+			query:      LeaderboardQuery{GuildID: "guild", Page: 2},
+			page:       2,
+			fields:     5,
+			firstField: "21. farmer21",
+		},
+		{
+			name:       "pages past the end show the last page",
+			query:      LeaderboardQuery{GuildID: "guild", Page: 7},
+			page:       2,
+			fields:     5,
+			firstField: "21. farmer21",
+		},
+		{
+			name:       "pages before the start show the first page",
+			query:      LeaderboardQuery{GuildID: "guild", Page: -1},
+			page:       0,
+			fields:     LeaderboardPageSize,
+			firstField: "1. farmer1",
+		},
+		{
+			name:        "highlighted user's page",
+			query:       LeaderboardQuery{GuildID: "guild", Highlight: "14", HighlightPage: true},
+			page:        1,
+			fields:      LeaderboardPageSize,
+			firstField:  "11. farmer11",
+			description: "You're #14 of 25",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := BuildLeaderboard(ctx, store, tt.query)
+			require.NoError(t, err)
+			require.Equal(t, tt.page, board.Page)
+			require.Equal(t, 3, board.Pages)
+			require.Len(t, board.Embed.Fields, tt.fields)
+			require.True(t, strings.HasPrefix(board.Embed.Fields[0].Name, tt.firstField), board.Embed.Fields[0].Name)
+			require.Equal(t, tt.description, board.Embed.Description)
+		})
+	}
+
+	t.Run("highlighted user is called out", func(t *testing.T) {
+		board, err := BuildLeaderboard(ctx, store, LeaderboardQuery{GuildID: "guild", Highlight: "14", HighlightPage: true})
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(board.Embed.Fields[3].Name, ":point_right: 14. farmer14"), board.Embed.Fields[3].Name)
+	})
+
+	t.Run("other metrics", func(t *testing.T) {
+		tx, err := store.Transaction(ctx)
+		require.NoError(t, err)
+		// the last placed user on soul eggs has prestiged the most
+		_, err = tx.CreateOrUpdateUser(datastore.User{EggIncID: "EI0025", Prestiges: 99, TrophyLevels: "5,5,4"})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		board, err := BuildLeaderboard(ctx, store, LeaderboardQuery{GuildID: "guild", Metric: "prestiges"})
+		require.NoError(t, err)
+		require.Equal(t, "Prestige Leaderboard", board.Embed.Title)
+		require.Equal(t, "prestiges", board.Metric)
+		require.Equal(t, "1. farmer25 99 prestiges", board.Embed.Fields[0].Name)
+
+		board, err = BuildLeaderboard(ctx, store, LeaderboardQuery{GuildID: "guild", Metric: "trophies"})
+		require.NoError(t, err)
+		require.Equal(t, "1. farmer25 14 trophy levels", board.Embed.Fields[0].Name)
+		require.True(t, strings.HasPrefix(board.Embed.Fields[0].Value, "2 diamond trophies"), board.Embed.Fields[0].Value)
+
+		_, err = BuildLeaderboard(ctx, store, LeaderboardQuery{GuildID: "guild", Metric: "vibes"})
+		require.Error(t, err)
+	})
+
+	t.Run("every metric builds", func(t *testing.T) {
+		for _, metric := range MetricNames {
+			board, err := BuildLeaderboard(ctx, store, LeaderboardQuery{GuildID: "guild", Metric: metric})
+			require.NoError(t, err, metric)
+			require.Len(t, board.Embed.Fields, LeaderboardPageSize, metric)
+		}
+	})
+
+	t.Run("empty guild", func(t *testing.T) {
+		board, err := BuildLeaderboard(ctx, store, LeaderboardQuery{GuildID: "empty"})
+		require.NoError(t, err)
+		require.Equal(t, 1, board.Pages)
+		require.Empty(t, board.Embed.Fields)
+	})
+}
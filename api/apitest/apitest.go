@@ -0,0 +1,285 @@
+// Package apitest provides a local stand-in for the Egg, Inc. API so the api
+// package and anything built on it can be exercised without network access.
+package apitest
+
+import (
+	"context"
+	"egg/api"
+	"egg/config"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// Endpoints served by Server, relative to its URL
+const (
+	PathFirstContact    = "/ei/first_contact"
+	PathCoopStatus      = "/ei/coop_status"
+	PathPeriodicals     = "/ei/get_periodicals"
+	PathArtifactsConfig = "/ei_afx/config"
+)
+
+// Fault describes a misbehaviour to inject into every request made to an endpoint
+type Fault struct {
+	// StatusCode, when non-zero, is returned instead of a canned response
+	StatusCode int
+	// Latency delays the response, or until the request is cancelled
+	Latency time.Duration
+	// Malformed replies with a body that is not valid base64 protobuf
+	Malformed bool
+}
+
+// Server is a fake Egg, Inc. API serving canned fixtures over HTTP
+type Server struct {
+	*httptest.Server
+
+	mu              sync.Mutex
+	backups         map[string]*api.FirstContact
+	coops           map[string]*api.CoopStatus
+	periodicals     *api.Periodicals
+	artifactsConfig *api.ArtifactsConfigurationResponse
+	faults          map[string]Fault
+	requests        map[string]int
+	// lastRequests holds the decoded body of the most recent request to each path
+	lastRequests map[string][]byte
+}
+
+// NewServer starts a fake Egg, Inc. API. Callers should Close it when finished.
+func NewServer() *Server {
+	s := &Server{
+		backups:         make(map[string]*api.FirstContact),
+		coops:           make(map[string]*api.CoopStatus),
+		periodicals:     &api.Periodicals{},
+		artifactsConfig: &api.ArtifactsConfigurationResponse{},
+		faults:          make(map[string]Fault),
+		requests:        make(map[string]int),
+		lastRequests:    make(map[string][]byte),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathFirstContact, s.wrap(PathFirstContact, s.firstContact))
+	mux.HandleFunc(PathCoopStatus, s.wrap(PathCoopStatus, s.coopStatus))
+	mux.HandleFunc(PathPeriodicals, s.wrap(PathPeriodicals, s.getPeriodicals))
+	mux.HandleFunc(PathArtifactsConfig, s.wrap(PathArtifactsConfig, s.getArtifactsConfig))
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// APIClient returns an api.Client pointed at the fake server
+func (s *Server) APIClient() *api.Client {
+	client := api.NewClient(config.API{BaseURL: s.URL})
+	client.HTTPClient = s.Client()
+	return client
+}
+
+// SetBackup registers a backup to be returned by first_contact for its EiUserId
+func (s *Server) SetBackup(backup *api.FirstContact_Payload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backups[backup.GetEiUserId()] = &api.FirstContact{Data: backup}
+}
+
+// SetCoopStatus registers a coop to be returned by coop_status for its contract ID and code
+func (s *Server) SetCoopStatus(status *api.CoopStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.coops[coopKey(status.GetContractId(), status.GetCode())] = status
+}
+
+// SetPeriodicals sets the response for get_periodicals
+func (s *Server) SetPeriodicals(periodicals *api.Periodicals) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.periodicals = periodicals
+}
+
+// SetArtifactsConfig sets the response for ei_afx/config
+func (s *Server) SetArtifactsConfig(config *api.ArtifactsConfigurationResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifactsConfig = config
+}
+
+// SetFault injects a fault into every subsequent request to path
+func (s *Server) SetFault(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = fault
+}
+
+// ClearFaults removes all injected faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string]Fault)
+}
+
+// Requests returns how many requests have been made to path
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// LastRequest decodes the most recent request made to path into msg, e.g. a FirstContactRequestPayload for
+// PathFirstContact, so tests can check what the client sent
+func (s *Server) LastRequest(path string, msg proto.Message) error {
+	s.mu.Lock()
+	data, ok := s.lastRequests[path]
+	s.mu.Unlock()
+	if !ok {
+		return errors.New(fmt.Sprintf("no request has been made to %s", path))
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+func (s *Server) firstContact(data []byte) (proto.Message, error) {
+	var req api.FirstContactRequestPayload
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if backup, ok := s.backups[req.GetEiUserId()]; ok {
+		return backup, nil
+	}
+
+	// the real API answers unknown IDs with an empty payload rather than an error
+	return &api.FirstContact{}, nil
+}
+
+func (s *Server) coopStatus(data []byte) (proto.Message, error) {
+	var req api.CoopStatusRequestPayload
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status, ok := s.coops[coopKey(req.GetContractId(), req.GetCode())]; ok {
+		return status, nil
+	}
+
+	return &api.CoopStatus{}, nil
+}
+
+func (s *Server) getPeriodicals(data []byte) (proto.Message, error) {
+	var req api.GetPeriodicalsRequestPayload
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.periodicals, nil
+}
+
+func (s *Server) getArtifactsConfig(data []byte) (proto.Message, error) {
+	var req api.ArtifactsConfigurationRequestPayload
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.artifactsConfig, nil
+}
+
+// wrap handles request decoding, fault injection and response framing around an endpoint handler
+func (s *Server) wrap(path string, handler func(data []byte) (proto.Message, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[path]++
+		fault := s.faults[path]
+		s.mu.Unlock()
+
+		// consume the body up front so a client hanging up cancels r.Context()
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if fault.Latency > 0 {
+			if err := sleep(r.Context(), fault.Latency); err != nil {
+				return
+			}
+		}
+
+		if fault.StatusCode != 0 {
+			http.Error(w, http.StatusText(fault.StatusCode), fault.StatusCode)
+			return
+		}
+
+		if fault.Malformed {
+			_, _ = w.Write([]byte("this is not base64 protobuf"))
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := base64.StdEncoding.DecodeString(r.PostForm.Get("data"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.lastRequests[path] = data
+		s.mu.Unlock()
+
+		resp, err := handler(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		body, err := encode(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		_, _ = w.Write(body)
+	}
+}
+
+// encode wraps a message in an AuthenticatedMessage and base64 encodes it, as the real API does
+func encode(msg proto.Message) ([]byte, error) {
+	inner, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	outer, err := proto.Marshal(&api.AuthenticatedMessage{Message: inner})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(outer)), nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func coopKey(contractID, code string) string {
+	return contractID + "/" + code
+}
@@ -0,0 +1,142 @@
+package apitest
+
+import "egg/api"
+
+// Backup returns a plausible mid-game backup for a player, suitable for SetBackup
+func Backup(eiUserID, userName string) *api.FirstContact_Payload {
+	return &api.FirstContact_Payload{
+		EiUserId: eiUserID,
+		UserName: userName,
+		Progress: &api.FirstContact_Payload_Progress{
+			SoulEggs:           1.5e18,
+			ProphecyEggs:       120,
+			LifetimeGoldenEggs: 250000,
+			FarmTrophyLevel: []api.TrophyType{
+				api.TrophyType_DIAMOND, api.TrophyType_DIAMOND, api.TrophyType_PLATINUM, api.TrophyType_GOLD,
+			},
+			EpicResearches: []*api.EpicResearch{
+				{Id: "soul_eggs", Level: 140},
+				{Id: "prophecy_bonus", Level: 5},
+			},
+		},
+		Stats: &api.FirstContact_Payload_Stats{
+			Prestiges:           42,
+			DroneTakedowns:      1000,
+			EliteDroneTakedowns: 25,
+			PiggyLevel:          8,
+			BoostsUsed:          300,
+		},
+		Artifacts: &api.FirstContact_Payload_Artifacts{
+			InventoryScore: 123456,
+		},
+		ArtifactsDb: &api.ArtifactsDB{
+			InventoryItems: []*api.ArtifactInventoryItem{
+				{ItemId: 1, Quantity: 1, Artifact: &api.CompleteArtifact{
+					Spec: &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_GREATER, Rarity: api.ArtifactSpec_LEGENDARY},
+					Stones: []*api.ArtifactSpec{
+						{Name: api.ArtifactSpec_PROPHECY_STONE, Level: api.ArtifactSpec_NORMAL},
+						{Name: api.ArtifactSpec_SOUL_STONE, Level: api.ArtifactSpec_LESSER},
+					},
+				}},
+				{ItemId: 2, Quantity: 1, Artifact: &api.CompleteArtifact{
+					Spec: &api.ArtifactSpec{Name: api.ArtifactSpec_TACHYON_DEFLECTOR, Level: api.ArtifactSpec_GREATER, Rarity: api.ArtifactSpec_EPIC},
+				}},
+				{ItemId: 3, Quantity: 2, Artifact: &api.CompleteArtifact{
+					Spec: &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_INFERIOR},
+				}},
+				{ItemId: 4, Quantity: 5, Artifact: &api.CompleteArtifact{
+					Spec: &api.ArtifactSpec{Name: api.ArtifactSpec_PROPHECY_STONE_FRAGMENT},
+				}},
+				{ItemId: 5, Quantity: 120, Artifact: &api.CompleteArtifact{
+					Spec: &api.ArtifactSpec{Name: api.ArtifactSpec_GOLD_METEORITE, Level: api.ArtifactSpec_LESSER},
+				}},
+			},
+			ActiveArtifactSets: []*api.ArtifactsDB_ActiveArtifactSet{
+				{Slots: []*api.ArtifactsDB_ActiveArtifactSlot{
+					{Occupied: true, ItemId: 1},
+					{Occupied: true, ItemId: 2},
+					{Occupied: false},
+				}},
+			},
+			MissionInfos: []*api.MissionInfo{
+				{
+					Ship: api.MissionInfo_HENERPRISE, Status: api.MissionInfo_EXPLORING, DurationType: api.MissionInfo_EPIC,
+					Identifier: "mission-henerprise", StartTimeDerived: 1646136000, DurationSeconds: 172800, SecondsRemaining: 86400, Capacity: 50,
+				},
+				{
+					Ship: api.MissionInfo_CHICKEN_HEAVY, Status: api.MissionInfo_RETURNED, DurationType: api.MissionInfo_SHORT,
+					Identifier: "mission-heavy", StartTimeDerived: 1646128800, DurationSeconds: 3600, Capacity: 14,
+				},
+				{Ship: api.MissionInfo_BCR, Status: api.MissionInfo_FUELING, DurationType: api.MissionInfo_LONG},
+			},
+			MissionArchive: []*api.MissionInfo{
+				{Ship: api.MissionInfo_HENERPRISE, Status: api.MissionInfo_ARCHIVED, DurationType: api.MissionInfo_EPIC},
+				{Ship: api.MissionInfo_HENERPRISE, Status: api.MissionInfo_ARCHIVED, DurationType: api.MissionInfo_EPIC},
+				{Ship: api.MissionInfo_HENERPRISE, Status: api.MissionInfo_ARCHIVED, DurationType: api.MissionInfo_SHORT},
+				{Ship: api.MissionInfo_BCR, Status: api.MissionInfo_ARCHIVED, DurationType: api.MissionInfo_LONG},
+			},
+			CraftingCounts: []*api.ArtifactsDB_CraftableArtifact{
+				{Spec: &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_GREATER}, Count: 10},
+			},
+			DiscoveredArtifacts: []*api.ArtifactSpec{
+				{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_GREATER, Rarity: api.ArtifactSpec_LEGENDARY},
+				{Name: api.ArtifactSpec_TACHYON_DEFLECTOR, Level: api.ArtifactSpec_GREATER, Rarity: api.ArtifactSpec_EPIC},
+			},
+		},
+	}
+}
+
+// ArtifactsConfig returns a cut down artifacts config, suitable for SetArtifactsConfig
+func ArtifactsConfig() *api.ArtifactsConfigurationResponse {
+	return &api.ArtifactsConfigurationResponse{
+		ArtifactParameters: []*api.ArtifactsConfigurationResponse_ArtifactParameters{
+			{
+				Spec:                &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_GREATER, Rarity: api.ArtifactSpec_COMMON},
+				CraftingPrice:       50000,
+				CraftingPriceLow:    10000,
+				CraftingPriceDomain: 100,
+				CraftingPriceCurve:  0.5,
+			},
+			{
+				Spec: &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_INFERIOR, Rarity: api.ArtifactSpec_COMMON},
+			},
+		},
+		MissionParameters: []*api.ArtifactsConfigurationResponse_MissionParameters{
+			{
+				Ship:                     api.MissionInfo_BCR,
+				LevelMissionRequirements: []uint32{4, 6},
+				Durations: []*api.ArtifactsConfigurationResponse_MissionParameters_Duration{
+					{DurationType: api.MissionInfo_SHORT, Seconds: 4 * 3600, Capacity: 10, LevelCapacityBump: 1, Quality: 1.4, LevelQualityBump: 0.1},
+				},
+			},
+			{
+				Ship:                     api.MissionInfo_HENERPRISE,
+				LevelMissionRequirements: []uint32{2, 3, 5},
+				Durations: []*api.ArtifactsConfigurationResponse_MissionParameters_Duration{
+					{DurationType: api.MissionInfo_TUTORIAL, Seconds: 60, Capacity: 1},
+					{DurationType: api.MissionInfo_SHORT, Seconds: 2 * 24 * 3600, Capacity: 40, LevelCapacityBump: 5, Quality: 3, LevelQualityBump: 0.25},
+					{DurationType: api.MissionInfo_EPIC, Seconds: 4 * 24 * 3600, Capacity: 50, LevelCapacityBump: 5, Quality: 4, LevelQualityBump: 0.25},
+				},
+			},
+			{Ship: api.MissionInfo_CHICKEN_ONE, LevelMissionRequirements: []uint32{4}},
+		},
+	}
+}
+
+// Periodicals returns two running events, one that has ended and a running sale, suitable for SetPeriodicals
+func Periodicals() *api.Periodicals {
+	return &api.Periodicals{
+		Events: &api.Periodicals_Events{
+			Events: []*api.Event{
+				{Id: "e4c8", EventType: "research-sale", Multiplier: 0.3, SecondsRemaining: 7200},
+				{Id: "a1f2", EventType: "earnings-boost", Multiplier: 2, SecondsRemaining: 86400, Message: "2x EARNINGS"},
+				{Id: "0ld0", EventType: "drone-boost", Multiplier: 5},
+			},
+		},
+		Sales: &api.Periodicals_Sales{
+			Sales: []*api.Sale{
+				{Id: "gametime-piggy-sale", SaleType: "piggy_break", SaleAmount: "40%", SecondsRemaining: 3600},
+			},
+		},
+	}
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"egg/format"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxFieldLength is the most Discord allows in an embed field's value
+const maxFieldLength = 1024
+
+// artifactFamily is every tier and rarity of one artifact, stone or ingredient
+type artifactFamily struct {
+	Name ArtifactSpec_Name
+	// Title is the name shown in game, without a tier
+	Title string
+	Type  ArtifactSpec_Type
+}
+
+// artifactFamilies are listed in the order the game shows them. Stone fragments are the first tier of their stone and
+// so aren't a family of their own.
+var artifactFamilies = []artifactFamily{
+	{ArtifactSpec_PUZZLE_CUBE, "Puzzle Cube", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_LUNAR_TOTEM, "Lunar Totem", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_DEMETERS_NECKLACE, "Demeter's Necklace", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_VIAL_MARTIAN_DUST, "Vial of Martian Dust", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_AURELIAN_BROOCH, "Aurelian Brooch", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_TUNGSTEN_ANKH, "Tungsten Ankh", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_ORNATE_GUSSET, "Gusset", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_NEODYMIUM_MEDALLION, "Neodymium Medallion", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_MERCURYS_LENS, "Mercury's Lens", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_BEAK_OF_MIDAS, "Beak of Midas", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_CARVED_RAINSTICK, "Carved Rainstick", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_INTERSTELLAR_COMPASS, "Interstellar Compass", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_SHIP_IN_A_BOTTLE, "Ship in a Bottle", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_TACHYON_DEFLECTOR, "Tachyon Deflector", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_QUANTUM_METRONOME, "Quantum Metronome", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_DILITHIUM_MONOCLE, "Dilithium Monocle", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_PHOENIX_FEATHER, "Phoenix Feather", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_THE_CHALICE, "The Chalice", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_BOOK_OF_BASAN, "Book of Basan", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_TITANIUM_ACTUATOR, "Titanium Actuator", ArtifactSpec_ARTIFACT},
+	{ArtifactSpec_LIGHT_OF_EGGENDIL, "Light of Eggendil", ArtifactSpec_ARTIFACT},
+
+	{ArtifactSpec_LUNAR_STONE, "Lunar Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_SHELL_STONE, "Shell Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_TACHYON_STONE, "Tachyon Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_TERRA_STONE, "Terra Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_SOUL_STONE, "Soul Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_DILITHIUM_STONE, "Dilithium Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_QUANTUM_STONE, "Quantum Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_LIFE_STONE, "Life Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_CLARITY_STONE, "Clarity Stone", ArtifactSpec_STONE},
+	{ArtifactSpec_PROPHECY_STONE, "Prophecy Stone", ArtifactSpec_STONE},
+
+	{ArtifactSpec_GOLD_METEORITE, "Gold Meteorite", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_TAU_CETI_GEODE, "Tau Ceti Geode", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_SOLAR_TITANIUM, "Solar Titanium", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_EXTRATERRESTRIAL_ALUMINUM, "Extraterrestrial Aluminum", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_ANCIENT_TUNGSTEN, "Ancient Tungsten", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_SPACE_ROCKS, "Space Rocks", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_ALIEN_WOOD, "Alien Wood", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_CENTAURIAN_STEEL, "Centaurian Steel", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_ERIDANI_FEATHER, "Eridani Feather", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_DRONE_PARTS, "Drone Parts", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_CELESTIAL_BRONZE, "Celestial Bronze", ArtifactSpec_INGREDIENT},
+	{ArtifactSpec_LALANDE_HIDE, "Lalande Hide", ArtifactSpec_INGREDIENT},
+}
+
+// stoneFragments maps each stone fragment to the stone it's the first tier of
+var stoneFragments = map[ArtifactSpec_Name]ArtifactSpec_Name{
+	ArtifactSpec_TACHYON_STONE_FRAGMENT:   ArtifactSpec_TACHYON_STONE,
+	ArtifactSpec_DILITHIUM_STONE_FRAGMENT: ArtifactSpec_DILITHIUM_STONE,
+	ArtifactSpec_SHELL_STONE_FRAGMENT:     ArtifactSpec_SHELL_STONE,
+	ArtifactSpec_LUNAR_STONE_FRAGMENT:     ArtifactSpec_LUNAR_STONE,
+	ArtifactSpec_SOUL_STONE_FRAGMENT:      ArtifactSpec_SOUL_STONE,
+	ArtifactSpec_PROPHECY_STONE_FRAGMENT:  ArtifactSpec_PROPHECY_STONE,
+	ArtifactSpec_QUANTUM_STONE_FRAGMENT:   ArtifactSpec_QUANTUM_STONE,
+	ArtifactSpec_TERRA_STONE_FRAGMENT:     ArtifactSpec_TERRA_STONE,
+	ArtifactSpec_LIFE_STONE_FRAGMENT:      ArtifactSpec_LIFE_STONE,
+	ArtifactSpec_CLARITY_STONE_FRAGMENT:   ArtifactSpec_CLARITY_STONE,
+}
+
+var familiesByName = indexArtifactFamilies()
+
+func indexArtifactFamilies() map[ArtifactSpec_Name]artifactFamily {
+	byName := make(map[ArtifactSpec_Name]artifactFamily, len(artifactFamilies))
+	for _, family := range artifactFamilies {
+		byName[family.Name] = family
+	}
+	return byName
+}
+
+// familyOf is the family an artifact, stone or ingredient belongs to. Anything the table doesn't know yet is treated
+// as an artifact named after its enum.
+func familyOf(name ArtifactSpec_Name) artifactFamily {
+	if stone, ok := stoneFragments[name]; ok {
+		name = stone
+	}
+	if family, ok := familiesByName[name]; ok {
+		return family
+	}
+
+	words := strings.Split(strings.ToLower(name.String()), "_")
+	for i, word := range words {
+		words[i] = titleCase(word)
+	}
+	return artifactFamily{Name: name, Title: strings.Join(words, " "), Type: ArtifactSpec_ARTIFACT}
+}
+
+// ArtifactTier is the tier the game shows for an artifact, stone or ingredient, counting from T1. Stone fragments are
+// T1 of their stone, so the stones themselves start at T2.
+func ArtifactTier(spec *ArtifactSpec) int {
+	if _, ok := stoneFragments[spec.GetName()]; ok {
+		return 1
+	}
+	if familyOf(spec.GetName()).Type == ArtifactSpec_STONE {
+		return int(spec.GetLevel()) + 2
+	}
+	return int(spec.GetLevel()) + 1
+}
+
+// ArtifactName is the short name players use for an artifact, e.g. T4L Book of Basan. Only artifacts come in
+// rarities, and common is left unsaid.
+func ArtifactName(spec *ArtifactSpec) string {
+	family := familyOf(spec.GetName())
+	rarity := ""
+	if family.Type == ArtifactSpec_ARTIFACT && spec.GetRarity() != ArtifactSpec_COMMON {
+		rarity = spec.GetRarity().String()[:1]
+	}
+	return fmt.Sprintf("T%d%s %s", ArtifactTier(spec), rarity, family.Title)
+}
+
+// equippedArtifacts describes the artifacts in the home farm's set along with the stones slotted into each
+func equippedArtifacts(db *ArtifactsDB) []string {
+	items := make(map[uint64]*ArtifactInventoryItem, len(db.GetInventoryItems()))
+	for _, item := range db.GetInventoryItems() {
+		items[item.GetItemId()] = item
+	}
+
+	// the first set is the home farm's; backups from before sets existed only have the one list of slots
+	slots := db.GetActiveArtifacts()
+	if sets := db.GetActiveArtifactSets(); len(sets) > 0 {
+		slots = sets[0].GetSlots()
+	}
+
+	var equipped []string
+	for _, slot := range slots {
+		item, ok := items[slot.GetItemId()]
+		if !slot.GetOccupied() || !ok {
+			continue
+		}
+
+		line := ArtifactName(item.GetArtifact().GetSpec())
+		if stones := item.GetArtifact().GetStones(); len(stones) > 0 {
+			names := make([]string, 0, len(stones))
+			for _, stone := range stones {
+				names = append(names, ArtifactName(stone))
+			}
+			line += " with " + strings.Join(names, ", ")
+		}
+		equipped = append(equipped, line)
+	}
+
+	return equipped
+}
+
+// inventoryByFamily counts the inventory by family and tier, with one line per family in the order the game shows them
+func inventoryByFamily(db *ArtifactsDB, artifactType ArtifactSpec_Type) []string {
+	counts := make(map[ArtifactSpec_Name]map[int]float64)
+	for _, item := range db.GetInventoryItems() {
+		spec := item.GetArtifact().GetSpec()
+		family := familyOf(spec.GetName())
+		if family.Type != artifactType {
+			continue
+		}
+		if counts[family.Name] == nil {
+			counts[family.Name] = make(map[int]float64)
+		}
+		counts[family.Name][ArtifactTier(spec)] += item.GetQuantity()
+	}
+
+	names := make([]ArtifactSpec_Name, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	order := make(map[ArtifactSpec_Name]int, len(artifactFamilies))
+	for i, family := range artifactFamilies {
+		order[family.Name] = i
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		oi, iKnown := order[names[i]]
+		oj, jKnown := order[names[j]]
+		if iKnown != jKnown {
+			return iKnown
+		}
+		return oi < oj
+	})
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		tiers := make([]int, 0, len(counts[name]))
+		for tier := range counts[name] {
+			tiers = append(tiers, tier)
+		}
+		sort.Ints(tiers)
+
+		held := make([]string, 0, len(tiers))
+		for _, tier := range tiers {
+			held = append(held, fmt.Sprintf("T%d ×%g", tier, math.Round(counts[name][tier])))
+		}
+		lines = append(lines, fmt.Sprintf("**%s:** %s", familyOf(name).Title, strings.Join(held, ", ")))
+	}
+
+	return lines
+}
+
+// legendaryCount is how many legendary artifacts are in the inventory
+func legendaryCount(db *ArtifactsDB) int {
+	var legendaries float64
+	for _, item := range db.GetInventoryItems() {
+		if item.GetArtifact().GetSpec().GetRarity() == ArtifactSpec_LEGENDARY {
+			legendaries += item.GetQuantity()
+		}
+	}
+	return int(math.Round(legendaries))
+}
+
+// fieldValue joins lines into an embed field's value, noting how many didn't fit
+func fieldValue(lines []string) string {
+	if len(lines) == 0 {
+		return "none"
+	}
+
+	var out strings.Builder
+	for n, line := range lines {
+		more := fmt.Sprintf("\n...and %d more", len(lines)-n)
+		if out.Len()+len(line)+1+len(more) > maxFieldLength {
+			out.WriteString(more)
+			break
+		}
+		if n > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(line)
+	}
+	return out.String()
+}
+
+// BuildArtifactsEmbed summarises a backup's artifacts: the equipped set with its stones, legendaries, the inventory by
+// family and tier and the game's inventory score
+func BuildArtifactsEmbed(backup *FirstContact_Payload, numbers format.Options) *discordgo.MessageEmbed {
+	db := backup.GetArtifactsDb()
+
+	return &discordgo.MessageEmbed{
+		Type:      discordgo.EmbedTypeRich,
+		Title:     fmt.Sprintf("%s's Artifacts", backup.GetUserName()),
+		Timestamp: time.Now().Format(time.RFC3339),
+		Color:     0x8700C3, // button purple
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Inventory score", Value: numbers.Number(backup.GetArtifacts().GetInventoryScore()), Inline: true},
+			{Name: "Legendaries", Value: fmt.Sprintf("%d", legendaryCount(db)), Inline: true},
+			{Name: "Discovered", Value: fmt.Sprintf("%d", len(db.GetDiscoveredArtifacts())), Inline: true},
+			{Name: "Equipped", Value: fieldValue(equippedArtifacts(db)), Inline: false},
+			{Name: "Artifacts", Value: fieldValue(inventoryByFamily(db, ArtifactSpec_ARTIFACT)), Inline: false},
+			{Name: "Stones", Value: fieldValue(inventoryByFamily(db, ArtifactSpec_STONE)), Inline: false},
+			{Name: "Ingredients", Value: fieldValue(inventoryByFamily(db, ArtifactSpec_INGREDIENT)), Inline: false},
+		},
+	}
+}
@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"egg/api"
+	"egg/api/apitest"
+	"egg/format"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactName(t *testing.T) {
+	tests := []struct {
+		name string
+		spec *api.ArtifactSpec
+		want string
+	}{
+		{
+			name: "legendary artifact",
+			spec: &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_GREATER, Rarity: api.ArtifactSpec_LEGENDARY},
+			want: "T4L Book of Basan",
+		},
+		{
+			name: "common artifact",
+			spec: &api.ArtifactSpec{Name: api.ArtifactSpec_LUNAR_TOTEM, Level: api.ArtifactSpec_NORMAL},
+			want: "T3 Lunar Totem",
+		},
+		{
+			name: "stone",
+			spec: &api.ArtifactSpec{Name: api.ArtifactSpec_PROPHECY_STONE, Level: api.ArtifactSpec_NORMAL},
+			want: "T4 Prophecy Stone",
+		},
+		{
+			name: "stone fragment",
+			spec: &api.ArtifactSpec{Name: api.ArtifactSpec_CLARITY_STONE_FRAGMENT},
+			want: "T1 Clarity Stone",
+		},
+		{
+			name: "ingredient",
+			spec: &api.ArtifactSpec{Name: api.ArtifactSpec_GOLD_METEORITE, Level: api.ArtifactSpec_NORMAL},
+			want: "T3 Gold Meteorite",
+		},
+		{
+			name: "unknown",
+			spec: &api.ArtifactSpec{Name: api.ArtifactSpec_UNKNOWN},
+			want: "T1 Unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, api.ArtifactName(tt.spec))
+		})
+	}
+}
+
+func TestBuildArtifactsEmbed(t *testing.T) {
+	embed := api.BuildArtifactsEmbed(apitest.Backup("EI1111", "akroh"), format.Default)
+	require.Equal(t, "akroh's Artifacts", embed.Title)
+
+	fields := make(map[string]string, len(embed.Fields))
+	for _, field := range embed.Fields {
+		fields[field.Name] = field.Value
+	}
+	require.Equal(t, "123.456k", fields["Inventory score"])
+	require.Equal(t, "1", fields["Legendaries"])
+	require.Equal(t, "2", fields["Discovered"])
+	require.Equal(t, "T4L Book of Basan with T4 Prophecy Stone, T3 Soul Stone\nT4E Tachyon Deflector", fields["Equipped"])
+	require.Equal(t, "**Tachyon Deflector:** T4 ×1\n**Book of Basan:** T1 ×2, T4 ×1", fields["Artifacts"])
+	require.Equal(t, "**Prophecy Stone:** T1 ×5", fields["Stones"])
+	require.Equal(t, "**Gold Meteorite:** T2 ×120", fields["Ingredients"])
+
+	t.Run("no artifacts yet", func(t *testing.T) {
+		embed := api.BuildArtifactsEmbed(&api.FirstContact_Payload{UserName: "new"}, format.Default)
+		for _, field := range embed.Fields {
+			require.NotEmpty(t, field.Value, field.Name)
+		}
+	})
+}
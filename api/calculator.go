@@ -0,0 +1,82 @@
+package api
+
+import (
+	"egg/datastore"
+	"egg/format"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxSoulFood is the highest level of the soul food epic research
+	MaxSoulFood = 140
+	// MaxProphecyBonus is the highest level of the prophecy bonus epic research
+	MaxProphecyBonus = 5
+	// MaxProphecyEggs is well past what the game hands out, and keeps the prophecy egg bonus from overflowing
+	MaxProphecyEggs = 1000
+)
+
+// ParseHumanNumber reads a number the way the game writes them: plain ("1500"), with a suffix
+// ("1.5k", "2.25Q") or in scientific notation ("1.5e18")
+func ParseHumanNumber(input string) (float64, error) {
+	return format.Parse(input)
+}
+
+// HypotheticalUser builds a user from the inputs of the EB calculator, checking the epic research levels are possible
+func HypotheticalUser(soulEggs string, prophecyEggs, soulFood, prophecyBonus int64) (datastore.User, error) {
+	se, err := ParseHumanNumber(soulEggs)
+	if err != nil {
+		return datastore.User{}, err
+	}
+
+	switch {
+	case prophecyEggs < 0:
+		return datastore.User{}, errors.New("prophecy eggs can't be negative")
+	case prophecyEggs > MaxProphecyEggs:
+		return datastore.User{}, errors.New(fmt.Sprintf("prophecy eggs go up to %d", MaxProphecyEggs))
+	case soulFood < 0 || soulFood > MaxSoulFood:
+		return datastore.User{}, errors.New(fmt.Sprintf("soul food research goes from 0 to %d", MaxSoulFood))
+	case prophecyBonus < 0 || prophecyBonus > MaxProphecyBonus:
+		return datastore.User{}, errors.New(fmt.Sprintf("prophecy bonus research goes from 0 to %d", MaxProphecyBonus))
+	}
+
+	user := datastore.User{
+		SoulEggs:      se,
+		ProphecyEggs:  int32(prophecyEggs),
+		SoulFood:      int32(soulFood),
+		ProphecyBonus: int32(prophecyBonus),
+	}
+	if eb, _ := calculateEB(user); math.IsNaN(eb) || math.IsInf(eb, 0) {
+		return datastore.User{}, errors.New("that's more earnings bonus than I can count. Try fewer soul eggs or prophecy eggs")
+	}
+	return user, nil
+}
+
+// BuildEBEmbed shows the earnings bonus and farmer rank a user's soul eggs, prophecy eggs and epic research add up to
+func BuildEBEmbed(user datastore.User, numbers format.Options) *discordgo.MessageEmbed {
+	rank := GetRank(user)
+
+	next := "There's nowhere left to climb"
+	if rank.NextName != "" {
+		next = fmt.Sprintf("%s at %s%%, which takes %s soul eggs (%s more)",
+			rank.NextName, numbers.Number(rank.NextEB), numbers.Number(rank.SoulEggsForNext), numbers.Number(rank.SoulEggsToNext))
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:  discordgo.EmbedTypeRich,
+		Title: "Earnings Bonus Calculator",
+		Description: fmt.Sprintf("%s soul eggs, %d prophecy eggs, soul food %d and prophecy bonus %d",
+			numbers.Number(user.SoulEggs), user.ProphecyEggs, user.SoulFood, user.ProphecyBonus),
+		Timestamp: time.Now().Format(time.RFC3339),
+		Color:     0x8700C3, // button purple
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Earnings bonus", Value: numbers.Number(rank.EB) + "%", Inline: true},
+			{Name: "Rank", Value: rank.Name, Inline: true},
+			{Name: "Next rank", Value: next, Inline: false},
+		},
+	}
+}
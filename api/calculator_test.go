@@ -0,0 +1,89 @@
+package api
+
+import (
+	"egg/format"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHumanNumber(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		err      bool
+	}{
+		{input: "1500", expected: 1500},
+		{input: "1,500", expected: 1500},
+		{input: "1.5k", expected: 1500},
+		{input: "2m", expected: 2e6},
+		{input: "3b", expected: 3e9},
+		{input: "1.5T", expected: 1.5e12},
+		{input: "1.5q", expected: 1.5e15},
+		{input: "1.5Q", expected: 1.5e18},
+		{input: " 1.5 Q ", expected: 1.5e18},
+		{input: "2s", expected: 2e21},
+		{input: "2S", expected: 2e24},
+		{input: "2o", expected: 2e27},
+		{input: "2N", expected: 2e30},
+		{input: "2d", expected: 2e33},
+		{input: "1.5e18", expected: 1.5e18},
+		{input: "1.5E18", expected: 1.5e18},
+		{input: "0", expected: 0},
+		{input: "", err: true},
+		{input: "Q", err: true},
+		{input: "1.5QQ", err: true},
+		{input: "1.5x", err: true},
+		{input: "lots", err: true},
+		{input: "-1Q", err: true},
+		{input: "NaN", err: true},
+		{input: "inf", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			value, err := ParseHumanNumber(tt.input)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.InEpsilon(t, tt.expected+1, value+1, 1e-9)
+		})
+	}
+}
+
+func TestParseHumanNumberRoundTrips(t *testing.T) {
+	for _, value := range []float64{1, 1000, 1.5e18, 2.25e21, 9.999e33} {
+		parsed, err := ParseHumanNumber(forPeople(value))
+		require.NoError(t, err)
+		require.InEpsilon(t, value, parsed, 1e-3)
+	}
+}
+
+func TestHypotheticalUser(t *testing.T) {
+	user, err := HypotheticalUser("1Q", 1, 70, 1)
+	require.NoError(t, err)
+	_, humanEB := calculateEB(user)
+	require.Equal(t, "84.800Q", humanEB)
+
+	embed := BuildEBEmbed(user, format.Default)
+	require.Equal(t, "84.800Q%", embed.Fields[0].Value)
+	require.Equal(t, "Exafarmer II", embed.Fields[1].Value)
+
+	for _, bad := range []struct {
+		se                  string
+		pe, sf, prophecyLvl int64
+	}{
+		{se: "nope", pe: 1},
+		{se: "1Q", pe: -1},
+		{se: "1Q", sf: 141},
+		{se: "1Q", prophecyLvl: 6},
+		{se: "1", pe: 10000},
+		{se: "1", pe: 1 << 32},
+		{se: "1e300", pe: MaxProphecyEggs, prophecyLvl: MaxProphecyBonus},
+	} {
+		_, err = HypotheticalUser(bad.se, bad.pe, bad.sf, bad.prophecyLvl)
+		require.Error(t, err)
+	}
+}
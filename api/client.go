@@ -0,0 +1,236 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"egg/config"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBaseURL is the root of the Egg, Inc. API
+	DefaultBaseURL = "https://www.auxbrain.com"
+	// DefaultClientVersion is the game client version reported to the Egg, Inc. API
+	DefaultClientVersion = uint32(37)
+	// DefaultTimeout is the per-request timeout used by clients built with NewClient
+	DefaultTimeout = 30 * time.Second
+	// DefaultArtifactsConfigTTL is how long clients built with NewClient reuse the artifacts config, which only
+	// changes with game updates
+	DefaultArtifactsConfigTTL = 6 * time.Hour
+
+	pathFirstContact = "/ei/first_contact"
+	pathCoopStatus   = "/ei/coop_status"
+	pathPeriodicals  = "/ei/get_periodicals"
+
+	pathArtifactsConfig = "/ei_afx/config"
+)
+
+// Client talks to the Egg, Inc. API
+type Client struct {
+	BaseURL       string
+	HTTPClient    *http.Client
+	ClientVersion uint32
+	Platform      Platform
+	DeviceID      string
+	// ArtifactsConfigTTL is how long ArtifactsConfig reuses a fetched config, which it never does when zero
+	ArtifactsConfigTTL time.Duration
+
+	mu                sync.Mutex
+	artifactsConfig   *ArtifactsConfigurationResponse
+	artifactsConfigAt time.Time
+}
+
+// NewClient returns a Client configured by cfg, falling back to the real Egg, Inc. API and sensible defaults
+// for anything cfg leaves unset
+func NewClient(cfg config.API) *Client {
+	client := &Client{
+		BaseURL:       DefaultBaseURL,
+		HTTPClient:    &http.Client{Timeout: DefaultTimeout},
+		ClientVersion: DefaultClientVersion,
+		Platform:      Platform_IOS,
+		DeviceID:      "IOS",
+
+		ArtifactsConfigTTL: DefaultArtifactsConfigTTL,
+	}
+
+	if cfg.BaseURL != "" {
+		client.BaseURL = cfg.BaseURL
+	}
+	if cfg.ClientVersion > 0 {
+		client.ClientVersion = uint32(cfg.ClientVersion)
+	}
+	if cfg.TimeoutSeconds > 0 {
+		client.HTTPClient.Timeout = cfg.Timeout()
+	}
+
+	return client
+}
+
+// GetBackup queries the Egg, Inc. API for a user's backup info
+func (c *Client) GetBackup(ctx context.Context, eiUID string) (*FirstContact_Payload, error) {
+	payload := &FirstContactRequestPayload{
+		EiUserId:      eiUID,
+		DeviceId:      c.DeviceID,
+		ClientVersion: c.ClientVersion,
+		Platform:      c.Platform,
+	}
+
+	responseBody := new(FirstContact)
+	if err := c.post(ctx, pathFirstContact, payload, responseBody); err != nil {
+		return &FirstContact_Payload{}, err
+	}
+
+	if responseBody.GetData() == nil {
+		return &FirstContact_Payload{}, errors.New(fmt.Sprintf("no backup found for user ID: %s", eiUID))
+	}
+
+	return responseBody.Data, nil
+}
+
+// GetCoopStatus queries the Egg, Inc. API for the status of a coop by contract ID and coop code
+func (c *Client) GetCoopStatus(ctx context.Context, contractID, code string) (*CoopStatus, error) {
+	payload := &CoopStatusRequestPayload{
+		ContractId: contractID,
+		Code:       code,
+	}
+
+	status := new(CoopStatus)
+	if err := c.post(ctx, pathCoopStatus, payload, status); err != nil {
+		return &CoopStatus{}, err
+	}
+
+	if status.GetContractId() == "" && len(status.GetMembers()) == 0 {
+		return &CoopStatus{}, errors.New(fmt.Sprintf("no coop found for contract '%s' with code '%s'", contractID, code))
+	}
+
+	return status, nil
+}
+
+// GetPeriodicals queries the Egg, Inc. API for the currently running contracts, events and sales
+func (c *Client) GetPeriodicals(ctx context.Context) (*Periodicals, error) {
+	payload := &GetPeriodicalsRequestPayload{
+		CurrentClientVersion: c.ClientVersion,
+		Rinfo: &BasicRequestInfo{
+			ClientVersion: c.ClientVersion,
+			Platform:      c.Platform.String(),
+		},
+	}
+
+	periodicals := new(Periodicals)
+	if err := c.post(ctx, pathPeriodicals, payload, periodicals); err != nil {
+		return &Periodicals{}, err
+	}
+
+	return periodicals, nil
+}
+
+// GetArtifactsConfig queries the Egg, Inc. API for the parameters of every artifact and spaceship, e.g. crafting prices
+// and mission durations
+func (c *Client) GetArtifactsConfig(ctx context.Context) (*ArtifactsConfigurationResponse, error) {
+	payload := &ArtifactsConfigurationRequestPayload{
+		ClientVersion: c.ClientVersion,
+		Rinfo: &BasicRequestInfo{
+			ClientVersion: c.ClientVersion,
+			Platform:      c.Platform.String(),
+		},
+	}
+
+	artifactsConfig := new(ArtifactsConfigurationResponse)
+	if err := c.post(ctx, pathArtifactsConfig, payload, artifactsConfig); err != nil {
+		return &ArtifactsConfigurationResponse{}, err
+	}
+
+	if len(artifactsConfig.GetArtifactParameters()) == 0 && len(artifactsConfig.GetMissionParameters()) == 0 {
+		return &ArtifactsConfigurationResponse{}, errors.New("the artifacts config came back empty")
+	}
+
+	return artifactsConfig, nil
+}
+
+// ArtifactsConfig returns the artifacts config, only fetching it again once ArtifactsConfigTTL has passed. When a
+// fetch fails the last config is returned instead, since a slightly stale config beats none at all.
+func (c *Client) ArtifactsConfig(ctx context.Context) (*ArtifactsConfigurationResponse, error) {
+	// holding the lock while fetching means concurrent commands share one request
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.artifactsConfig != nil && time.Since(c.artifactsConfigAt) < c.ArtifactsConfigTTL {
+		return c.artifactsConfig, nil
+	}
+
+	artifactsConfig, err := c.GetArtifactsConfig(ctx)
+	if err != nil {
+		if c.artifactsConfig != nil {
+			return c.artifactsConfig, nil
+		}
+		return artifactsConfig, err
+	}
+
+	c.artifactsConfig, c.artifactsConfigAt = artifactsConfig, time.Now()
+	return artifactsConfig, nil
+}
+
+// post sends a base64 encoded request to an Egg, Inc. API endpoint and decodes the authenticated response into out
+func (c *Client) post(ctx context.Context, path string, in, out proto.Message) error {
+	reqBin, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{"data": {base64.StdEncoding.EncodeToString(reqBin)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.BaseURL, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "calling %s", path)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "reading response from %s", path)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("%s returned %d: %s", path, resp.StatusCode, bytes.TrimSpace(body)))
+	}
+
+	enc := base64.StdEncoding
+	buf := make([]byte, enc.DecodedLen(len(body)))
+	decoded, err := enc.Decode(buf, bytes.TrimSpace(body))
+	if err != nil {
+		return errors.Wrapf(err, "decoding response from %s", path)
+	}
+
+	authenticatedMsg := new(AuthenticatedMessage)
+	if err = proto.Unmarshal(buf[:decoded], authenticatedMsg); err != nil {
+		return errors.Wrapf(err, "unmarshalling response from %s", path)
+	}
+
+	if err = proto.Unmarshal(authenticatedMsg.Message, out); err != nil {
+		return errors.Wrapf(err, "unmarshalling message from %s", path)
+	}
+
+	return nil
+}
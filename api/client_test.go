@@ -0,0 +1,251 @@
+package api_test
+
+import (
+	"context"
+	"egg/api"
+	"egg/api/apitest"
+	"egg/config"
+	"egg/datastore"
+	"egg/format"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestNewClient(t *testing.T) {
+	defaults := api.NewClient(config.API{})
+	require.Equal(t, api.DefaultBaseURL, defaults.BaseURL)
+	require.Equal(t, api.DefaultClientVersion, defaults.ClientVersion)
+	require.Equal(t, api.DefaultTimeout, defaults.HTTPClient.Timeout)
+
+	configured := api.NewClient(config.API{BaseURL: "http://localhost:8080", ClientVersion: 40, TimeoutSeconds: 5})
+	require.Equal(t, "http://localhost:8080", configured.BaseURL)
+	require.Equal(t, uint32(40), configured.ClientVersion)
+	require.Equal(t, 5*time.Second, configured.HTTPClient.Timeout)
+}
+
+func TestClientGetBackup(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	server.SetBackup(apitest.Backup("EI1234", "akroh"))
+	client := server.APIClient()
+	client.ClientVersion = 42
+	client.Platform = api.Platform_DROID
+	client.DeviceID = "test-device"
+
+	t.Run("known user", func(t *testing.T) {
+		backup, err := client.GetBackup(context.Background(), "EI1234")
+		require.NoError(t, err)
+		require.Equal(t, "EI1234", backup.EiUserId)
+		require.Equal(t, "akroh", backup.UserName)
+
+		var received api.FirstContactRequestPayload
+		require.NoError(t, server.LastRequest(apitest.PathFirstContact, &received))
+		require.Equal(t, "EI1234", received.GetEiUserId())
+		require.Equal(t, uint32(42), received.GetClientVersion())
+		require.Equal(t, api.Platform_DROID, received.GetPlatform())
+		require.Equal(t, "test-device", received.GetDeviceId())
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := client.GetBackup(context.Background(), "EI0000")
+		require.Error(t, err)
+	})
+
+	require.Equal(t, 2, server.Requests(apitest.PathFirstContact))
+}
+
+func TestClientGetCoopStatus(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	server.SetCoopStatus(&api.CoopStatus{
+		ContractId: "spring-2022",
+		Code:       "eggs",
+		EggsLaid:   3e15,
+		Members: []*api.CoopStatus_Member{
+			{Name: "akroh", EggsLaid: 2e15, EggsPerSecond: 1e9, Active: true, Tokens: 4},
+			{Name: "sleepy", EggsLaid: 1e15},
+		},
+		SecondsUntilProductionDeadline: 90000,
+	})
+	client := server.APIClient()
+
+	t.Run("known coop", func(t *testing.T) {
+		status, err := client.GetCoopStatus(context.Background(), "spring-2022", "eggs")
+		require.NoError(t, err)
+		require.Len(t, status.Members, 2)
+
+		embed := api.BuildCoopStatusEmbed(status, format.Default)
+		require.Len(t, embed.Fields, 2)
+		require.Equal(t, "1. akroh", embed.Fields[0].Name)
+		require.Equal(t, "2. sleepy :zzz:", embed.Fields[1].Name)
+		require.Contains(t, embed.Description, "1 snoozing")
+		require.Contains(t, embed.Description, "1d 1h 0m")
+	})
+
+	t.Run("unknown coop", func(t *testing.T) {
+		_, err := client.GetCoopStatus(context.Background(), "spring-2022", "nope")
+		require.Error(t, err)
+	})
+}
+
+func TestClientGetPeriodicals(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	expiry := float64(time.Now().Add(48 * time.Hour).Unix())
+	server.SetPeriodicals(&api.Periodicals{
+		Contracts: &api.Periodicals_Contracts{
+			Contracts: []*api.ContractProperties{
+				{
+					Id:                   "rocket-launch",
+					Name:                 "Rocket Launch",
+					EggType:              api.EggType_ROCKET_FUEL,
+					MaxCoopSize:          10,
+					ExpiryTimestamp:      expiry,
+					DurationSeconds:      3 * 24 * 3600,
+					TokenIntervalMinutes: 30,
+					RewardTiers: []*api.ContractProperties_RewardTier{
+						{Rewards: []*api.Reward{{Goal: 5e14, Type: api.RewardType_GOLDEN_EGG, Count: 500}}},
+						{Rewards: []*api.Reward{{Goal: 1e14, Type: api.RewardType_PROPHECY_EGG, Count: 1}}},
+					},
+				},
+				{Id: "debug", Debug: true, ExpiryTimestamp: expiry},
+				{Id: "expired", ExpiryTimestamp: 1},
+			},
+		},
+	})
+
+	periodicals, err := server.APIClient().GetPeriodicals(context.Background())
+	require.NoError(t, err)
+	require.Len(t, periodicals.GetContracts().GetContracts(), 3)
+
+	var received api.GetPeriodicalsRequestPayload
+	require.NoError(t, server.LastRequest(apitest.PathPeriodicals, &received))
+	require.Equal(t, api.DefaultClientVersion, received.GetCurrentClientVersion())
+	require.Equal(t, api.DefaultClientVersion, received.GetRinfo().GetClientVersion())
+	require.Equal(t, api.Platform_IOS.String(), received.GetRinfo().GetPlatform())
+
+	embed := api.BuildContractsEmbed(periodicals, format.Default)
+	require.Len(t, embed.Fields, 1)
+	require.Equal(t, "Rocket Launch (rocket-launch)", embed.Fields[0].Name)
+	require.Contains(t, embed.Fields[0].Value, "Rocket Fuel")
+	require.Contains(t, embed.Fields[0].Value, "**Elite:** 500.000T (500 GE)")
+	require.Contains(t, embed.Fields[0].Value, "**Standard:** 100.000T (1 PE)")
+}
+
+func TestClientFaults(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	server.SetBackup(apitest.Backup("EI1234", "akroh"))
+	client := server.APIClient()
+
+	t.Run("error status", func(t *testing.T) {
+		server.SetFault(apitest.PathFirstContact, apitest.Fault{StatusCode: http.StatusInternalServerError})
+		defer server.ClearFaults()
+
+		_, err := client.GetBackup(context.Background(), "EI1234")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "500")
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		server.SetFault(apitest.PathFirstContact, apitest.Fault{Malformed: true})
+		defer server.ClearFaults()
+
+		_, err := client.GetBackup(context.Background(), "EI1234")
+		require.Error(t, err)
+	})
+
+	t.Run("latency past the deadline", func(t *testing.T) {
+		server.SetFault(apitest.PathFirstContact, apitest.Fault{Latency: time.Second})
+		defer server.ClearFaults()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := client.GetBackup(ctx, "EI1234")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestRegisterFlow(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	server.SetBackup(apitest.Backup("EI1234", "akroh"))
+
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+	store := datastore.Database{DB: db}
+
+	ctx := context.Background()
+	backup, err := server.APIClient().GetBackup(ctx, "EI1234")
+	require.NoError(t, err)
+
+	user, err := api.AddUserToDatabase(ctx, store, backup, "guild-a", "1234567890", "krohmag")
+	require.NoError(t, err)
+	_, err = api.AddUserToDatabase(ctx, store, backup, "guild-b", "1234567890", "krohmag")
+	require.NoError(t, err)
+	require.Equal(t, "akroh", user.GameAccountName)
+	require.Equal(t, int32(140), user.SoulFood)
+	require.Equal(t, int32(5), user.ProphecyBonus)
+	require.Equal(t, int32(120), user.ProphecyEggs)
+	require.Equal(t, int64(250000), user.GoldenEggs)
+	require.Equal(t, int32(42), user.Prestiges)
+	require.Equal(t, int32(25), user.EliteDroneTakedowns)
+	require.Equal(t, []int32{5, 5, 4, 3}, user.Trophies())
+
+	require.Equal(t, "1234567890", user.DiscordID)
+
+	accounts, err := api.GetDiscordUsers(ctx, store, "1234567890", "krohmag")
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	_, err = api.GetDiscordUsers(ctx, store, "0987654321", "someone")
+	require.ErrorIs(t, err, api.ErrNotRegistered)
+
+	t.Run("a datastore failure isn't mistaken for not being registered", func(t *testing.T) {
+		db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+		require.NoError(t, err)
+		require.NoError(t, datastore.Migrate(db))
+		// only looking the caller up by Discord ID fails
+		require.NoError(t, db.Callback().Query().Before("gorm:query").Register("fail_discord_id", func(tx *gorm.DB) {
+			if strings.Contains(fmt.Sprint(tx.Statement.Clauses["WHERE"].Expression), "1234567890") {
+				_ = tx.AddError(errors.New("connection reset"))
+			}
+		}))
+
+		_, err = api.GetDiscordUsers(ctx, datastore.Database{DB: db}, "1234567890", "krohmag")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, api.ErrNotRegistered)
+	})
+
+	// a username collision isn't enough to remove someone else's registration
+	require.Error(t, api.RemoveUserFromDatabase(ctx, store, "guild-a", "EI1234", "0987654321", "krohmag"))
+
+	// leaving one guild keeps the registration for the other
+	require.NoError(t, api.RemoveUserFromDatabase(ctx, store, "guild-a", "EI1234", "1234567890", "renamed"))
+	tx, err := store.Transaction(ctx)
+	require.NoError(t, err)
+	guildIDs, err := tx.GetUserGuildIDs("EI1234")
+	require.NoError(t, err)
+	require.Equal(t, []string{"guild-b"}, guildIDs)
+	require.NoError(t, tx.Commit())
+
+	require.NoError(t, api.RemoveUserFromDatabase(ctx, store, "guild-b", "EI1234", "1234567890", "renamed"))
+	tx, err = store.Transaction(ctx)
+	require.NoError(t, err)
+	_, err = tx.GetUserByEggIncUserID("EI1234")
+	require.Error(t, err)
+	require.NoError(t, tx.Rollback())
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"egg/format"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// BuildContractsEmbed renders the contracts currently on offer as a Discord embed
+func BuildContractsEmbed(periodicals *Periodicals, numbers format.Options) *discordgo.MessageEmbed {
+	now := float64(time.Now().Unix())
+
+	contracts := make([]*ContractProperties, 0)
+	for _, contract := range periodicals.GetContracts().GetContracts() {
+		if contract.GetDebug() || contract.GetExpiryTimestamp() < now {
+			continue
+		}
+		contracts = append(contracts, contract)
+	}
+
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].GetExpiryTimestamp() < contracts[j].GetExpiryTimestamp()
+	})
+
+	embedFields := make([]*discordgo.MessageEmbedField, 0)
+	for _, contract := range contracts {
+		if len(embedFields) == maxEmbedFields {
+			break
+		}
+
+		lines := []string{
+			fmt.Sprintf("**Egg:** %s | **Max coop size:** %d", eggName(contract.GetEggType()), contract.GetMaxCoopSize()),
+			fmt.Sprintf("**Duration:** %s | **Token every:** %gm", humanDuration(contract.GetDurationSeconds()), contract.GetTokenIntervalMinutes()),
+			fmt.Sprintf("**Expires:** <t:%d:R>", int64(contract.GetExpiryTimestamp())),
+		}
+
+		// reward_tiers holds elite then standard; older contracts only carry the elite rewards
+		tiers := contract.GetRewardTiers()
+		switch {
+		case len(tiers) > 0:
+			lines = append(lines, fmt.Sprintf("**Elite:** %s", rewardSummary(tiers[0].GetRewards(), numbers)))
+			if len(tiers) > 1 {
+				lines = append(lines, fmt.Sprintf("**Standard:** %s", rewardSummary(tiers[1].GetRewards(), numbers)))
+			}
+		case len(contract.GetRewards()) > 0:
+			lines = append(lines, fmt.Sprintf("**Elite:** %s", rewardSummary(contract.GetRewards(), numbers)))
+		}
+
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("%s (%s)", contract.GetName(), contract.GetId()),
+			Value:  strings.Join(lines, "\n"),
+			Inline: false,
+		})
+	}
+
+	description := ""
+	if len(embedFields) == 0 {
+		description = "There are no contracts on offer right now"
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       "Current Contracts",
+		Description: description,
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Color:       0x8700C3, // button purple
+		Fields:      embedFields,
+	}
+}
+
+// rewardSummary lists a reward tier's goals along with what each one pays out
+func rewardSummary(rewards []*Reward, numbers format.Options) string {
+	goals := make([]string, 0, len(rewards))
+	for _, reward := range rewards {
+		goals = append(goals, fmt.Sprintf("%s (%s)", numbers.Number(reward.GetGoal()), rewardName(reward, numbers)))
+	}
+	return strings.Join(goals, " → ")
+}
+
+func rewardName(reward *Reward, numbers format.Options) string {
+	switch reward.GetType() {
+	case RewardType_GOLDEN_EGG:
+		return fmt.Sprintf("%g GE", reward.GetCount())
+	case RewardType_SOUL_EGG:
+		return fmt.Sprintf("%s SE", numbers.Number(reward.GetCount()))
+	case RewardType_PROPHECY_EGG:
+		return fmt.Sprintf("%g PE", reward.GetCount())
+	case RewardType_BOOST_TOKEN:
+		return fmt.Sprintf("%g tokens", reward.GetCount())
+	case RewardType_PIGGY_GOLDEN_EGG:
+		return fmt.Sprintf("%g piggy GE", reward.GetCount())
+	case RewardType_EPIC_RESEARCH, RewardType_BOOST, RewardType_ARTIFACT, RewardType_ARTIFACT_CASE:
+		return fmt.Sprintf("%gx %s", reward.GetCount(), strings.ReplaceAll(reward.GetName(), "_", " "))
+	default:
+		return strings.ToLower(strings.ReplaceAll(reward.GetType().String(), "_", " "))
+	}
+}
+
+// eggName turns an EggType into the name shown in game, e.g. ROCKET_FUEL becomes Rocket Fuel
+func eggName(egg EggType) string {
+	words := strings.Split(strings.ToLower(egg.String()), "_")
+	for i, word := range words {
+		words[i] = titleCase(word)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"egg/format"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxEmbedFields is the most fields Discord accepts on a single embed
+const maxEmbedFields = 25
+
+// BuildCoopStatusEmbed renders a coop's members and progress as a Discord embed
+func BuildCoopStatusEmbed(status *CoopStatus, numbers format.Options) *discordgo.MessageEmbed {
+	members := make([]*CoopStatus_Member, len(status.GetMembers()))
+	copy(members, status.GetMembers())
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].GetEggsLaid() > members[j].GetEggsLaid()
+	})
+
+	var totalRate float64
+	var snoozers int
+	embedFields := make([]*discordgo.MessageEmbedField, 0)
+	for i, member := range members {
+		totalRate += member.GetEggsPerSecond()
+
+		name := member.GetName()
+		if !member.GetActive() {
+			snoozers++
+			name = fmt.Sprintf("%s :zzz:", name)
+		}
+
+		if len(embedFields) == maxEmbedFields {
+			continue
+		}
+
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Name: fmt.Sprintf("%d. %s", i+1, name),
+			Value: fmt.Sprintf("%s eggs laid | %s/hr | %d tokens",
+				numbers.Number(member.GetEggsLaid()), numbers.Number(member.GetEggsPerSecond()*3600), member.GetTokens()),
+			Inline: false,
+		})
+	}
+
+	description := []string{
+		fmt.Sprintf("**Eggs laid:** %s", numbers.Number(status.GetEggsLaid())),
+		fmt.Sprintf("**Laying rate:** %s/hr", numbers.Number(totalRate*3600)),
+		fmt.Sprintf("**Members:** %d (%d snoozing)", len(members), snoozers),
+		fmt.Sprintf("**Time remaining:** %s", humanDuration(status.GetSecondsUntilProductionDeadline())),
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       fmt.Sprintf("%s / %s", status.GetContractId(), status.GetCode()),
+		Description: strings.Join(description, "\n"),
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Color:       0x8700C3, // button purple
+		Fields:      embedFields,
+	}
+}
+
+// humanDuration renders a number of seconds as days, hours and minutes
+func humanDuration(seconds float64) string {
+	if seconds <= 0 {
+		return "expired"
+	}
+
+	d := time.Duration(math.Round(seconds)) * time.Second
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"egg/format"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// MaxCraftCount is the most crafts the calculator will add up at once
+const MaxCraftCount = 10000
+
+// artifactNamePattern reads names like ArtifactName writes them, e.g. T4L Book of Basan, with the rarity optional
+var artifactNamePattern = regexp.MustCompile(`(?i)^t([1-9])[crel]?\s+(.+)$`)
+
+// ParseArtifactName reads an artifact, stone or ingredient written the way players write them, e.g. "T4 Book of
+// Basan" or "t2 prophecy stone". Any rarity given is ignored, since it doesn't change what crafting costs.
+func ParseArtifactName(input string) (*ArtifactSpec, error) {
+	match := artifactNamePattern.FindStringSubmatch(strings.TrimSpace(input))
+	if match == nil {
+		return nil, errors.New(fmt.Sprintf("'%s' isn't an artifact I understand. Try something like T4 Book of Basan", input))
+	}
+	tier, _ := strconv.Atoi(match[1])
+
+	title := strings.Join(strings.Fields(match[2]), " ")
+	for _, family := range artifactFamilies {
+		if !strings.EqualFold(family.Title, title) {
+			continue
+		}
+
+		switch {
+		case family.Type == ArtifactSpec_STONE && tier == 1:
+			for fragment, stone := range stoneFragments {
+				if stone == family.Name {
+					return &ArtifactSpec{Name: fragment}, nil
+				}
+			}
+		case family.Type == ArtifactSpec_STONE:
+			return &ArtifactSpec{Name: family.Name, Level: ArtifactSpec_Level(tier - 2)}, nil
+		default:
+			return &ArtifactSpec{Name: family.Name, Level: ArtifactSpec_Level(tier - 1)}, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("'%s' isn't an artifact I know. Try something like T4 Book of Basan", title))
+}
+
+// craftingParameters finds the config for an artifact at a given level. Crafting prices are the same at every rarity.
+func craftingParameters(artifactsConfig *ArtifactsConfigurationResponse, spec *ArtifactSpec) (*ArtifactsConfigurationResponse_ArtifactParameters, bool) {
+	for _, params := range artifactsConfig.GetArtifactParameters() {
+		if params.GetSpec().GetName() == spec.GetName() && params.GetSpec().GetLevel() == spec.GetLevel() && params.GetCraftingPrice() > 0 {
+			return params, true
+		}
+	}
+	return nil, false
+}
+
+// CraftingPrice is how many golden eggs the next craft of an artifact costs, given how many times it has been crafted
+// before. The price falls from crafting_price towards crafting_price_low along crafting_price_curve, reaching the low
+// price after crafting_price_domain crafts.
+func CraftingPrice(params *ArtifactsConfigurationResponse_ArtifactParameters, crafted uint32) float64 {
+	high, low := params.GetCraftingPrice(), params.GetCraftingPriceLow()
+	progress := 1.0
+	if domain := params.GetCraftingPriceDomain(); domain > 0 {
+		progress = math.Min(1, float64(crafted)/float64(domain))
+	}
+	return math.Floor(high - (high-low)*math.Pow(progress, params.GetCraftingPriceCurve()))
+}
+
+// craftedCount is how many times a backup has crafted an artifact at a given level
+func craftedCount(backup *FirstContact_Payload, spec *ArtifactSpec) uint32 {
+	for _, counted := range backup.GetArtifactsDb().GetCraftingCounts() {
+		if counted.GetSpec().GetName() == spec.GetName() && counted.GetSpec().GetLevel() == spec.GetLevel() {
+			return counted.GetCount()
+		}
+	}
+	return 0
+}
+
+// CraftQuote is what crafting an artifact costs an account
+type CraftQuote struct {
+	Spec *ArtifactSpec
+	// Crafted is how many times the account has crafted it before
+	Crafted uint32
+	// Count is how many more crafts Total covers
+	Count int
+	// Next is the price of the very next craft
+	Next float64
+	// Total is the price of the next Count crafts
+	Total float64
+}
+
+// QuoteCraft works out the golden egg cost of an account's next craft of an artifact and of crafting it count times
+func QuoteCraft(artifactsConfig *ArtifactsConfigurationResponse, backup *FirstContact_Payload, spec *ArtifactSpec, count int) (CraftQuote, error) {
+	if count < 1 || count > MaxCraftCount {
+		return CraftQuote{}, errors.New(fmt.Sprintf("the number of crafts has to be between 1 and %d", MaxCraftCount))
+	}
+
+	params, ok := craftingParameters(artifactsConfig, spec)
+	if !ok {
+		return CraftQuote{}, errors.New(fmt.Sprintf("%s can't be crafted", ArtifactName(spec)))
+	}
+
+	quote := CraftQuote{Spec: spec, Crafted: craftedCount(backup, spec), Count: count}
+	quote.Next = CraftingPrice(params, quote.Crafted)
+	for n := 0; n < count; n++ {
+		quote.Total += CraftingPrice(params, quote.Crafted+uint32(n))
+	}
+
+	return quote, nil
+}
+
+// BuildCraftEmbed shows what crafting an artifact costs an account
+func BuildCraftEmbed(accountName string, quote CraftQuote, numbers format.Options) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       fmt.Sprintf("Crafting %s", ArtifactName(quote.Spec)),
+		Description: fmt.Sprintf("%s has crafted it %d times", accountName, quote.Crafted),
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Color:       0x8700C3, // button purple
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Next craft", Value: numbers.Number(quote.Next) + " GE", Inline: true},
+			{Name: fmt.Sprintf("Next %d crafts", quote.Count), Value: numbers.Number(quote.Total) + " GE", Inline: true},
+		},
+	}
+}
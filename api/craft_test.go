@@ -0,0 +1,88 @@
+package api_test
+
+import (
+	"context"
+	"egg/api"
+	"egg/api/apitest"
+	"egg/format"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArtifactName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  *api.ArtifactSpec
+		err   bool
+	}{
+		{input: "T4 Book of Basan", want: &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_GREATER}},
+		{input: "t4l  book of basan ", want: &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN, Level: api.ArtifactSpec_GREATER}},
+		{input: "T3 Prophecy Stone", want: &api.ArtifactSpec{Name: api.ArtifactSpec_PROPHECY_STONE, Level: api.ArtifactSpec_LESSER}},
+		{input: "T1 Prophecy Stone", want: &api.ArtifactSpec{Name: api.ArtifactSpec_PROPHECY_STONE_FRAGMENT}},
+		{input: "T2 Gold Meteorite", want: &api.ArtifactSpec{Name: api.ArtifactSpec_GOLD_METEORITE, Level: api.ArtifactSpec_LESSER}},
+		{input: "Book of Basan", err: true},
+		{input: "T4 Book of Bacon", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			spec, err := api.ParseArtifactName(tt.input)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want.GetName(), spec.GetName())
+			require.Equal(t, tt.want.GetLevel(), spec.GetLevel())
+			// what's read back is written the same way
+			require.Equal(t, api.ArtifactName(tt.want), api.ArtifactName(spec))
+		})
+	}
+}
+
+func TestCraftingPrice(t *testing.T) {
+	params := apitest.ArtifactsConfig().GetArtifactParameters()[0]
+	require.Equal(t, float64(50000), api.CraftingPrice(params, 0))
+	// a quarter of the way along a square root curve is half way down
+	require.Equal(t, float64(30000), api.CraftingPrice(params, 25))
+	require.Equal(t, float64(10000), api.CraftingPrice(params, 100))
+	require.Equal(t, float64(10000), api.CraftingPrice(params, 500))
+}
+
+func TestQuoteCraft(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	server.SetArtifactsConfig(apitest.ArtifactsConfig())
+
+	artifactsConfig, err := server.APIClient().GetArtifactsConfig(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, server.Requests(apitest.PathArtifactsConfig))
+
+	backup := apitest.Backup("EI1111", "akroh")
+	spec, err := api.ParseArtifactName("T4 Book of Basan")
+	require.NoError(t, err)
+
+	quote, err := api.QuoteCraft(artifactsConfig, backup, spec, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint32(10), quote.Crafted)
+	params := apitest.ArtifactsConfig().GetArtifactParameters()[0]
+	require.Equal(t, api.CraftingPrice(params, 10), quote.Next)
+	require.Equal(t, api.CraftingPrice(params, 10)+api.CraftingPrice(params, 11), quote.Total)
+
+	embed := api.BuildCraftEmbed("akroh", quote, format.Default)
+	require.Equal(t, "Crafting T4 Book of Basan", embed.Title)
+	require.Equal(t, "akroh has crafted it 10 times", embed.Description)
+	require.Equal(t, "Next 2 crafts", embed.Fields[1].Name)
+
+	_, err = api.QuoteCraft(artifactsConfig, backup, &api.ArtifactSpec{Name: api.ArtifactSpec_BOOK_OF_BASAN}, 1)
+	require.EqualError(t, err, "T1 Book of Basan can't be crafted")
+	_, err = api.QuoteCraft(artifactsConfig, backup, spec, 0)
+	require.Error(t, err)
+
+	t.Run("empty config", func(t *testing.T) {
+		server.SetArtifactsConfig(&api.ArtifactsConfigurationResponse{})
+		_, err := server.APIClient().GetArtifactsConfig(context.Background())
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Announcement is an event or sale running in the game, as posted to the announcement channels
+type Announcement struct {
+	// Identifier is the game's ID prefixed with whether it's an event or a sale, since the two aren't told apart
+	// otherwise
+	Identifier string
+	Title      string
+	Detail     string
+	EndsAt     time.Time
+}
+
+// eventNames is how the game describes each event_type. Types missing here are title-cased.
+var eventNames = map[string]string{
+	"boost-duration":     "Boost Duration",
+	"boost-sale":         "Boost Sale",
+	"crafting-sale":      "Crafting Sale",
+	"drone-boost":        "Drone Boost",
+	"earnings-boost":     "Earnings Boost",
+	"epic-research-sale": "Epic Research Sale",
+	"gift-boost":         "Gift Boost",
+	"hab-sale":           "Hab Sale",
+	"mission-capacity":   "Mission Capacity",
+	"mission-duration":   "Mission Duration",
+	"mission-fuel":       "Mission Fuel",
+	"piggy-boost":        "Piggy Growth",
+	"piggy-cap-boost":    "Unlimited Piggy",
+	"prestige-boost":     "Prestige Boost",
+	"research-sale":      "Research Sale",
+	"shell-sale":         "Shell Sale",
+	"vehicle-sale":       "Vehicle Sale",
+}
+
+// saleNames is how the game describes each sale_type. Types missing here are title-cased.
+var saleNames = map[string]string{
+	"piggy_break":   "Piggy Bank Break",
+	"golden_eggs":   "Golden Egg Sale",
+	"pro_permit":    "Pro Permit Sale",
+	"starter_pack":  "Starter Pack Sale",
+	"ultra_sub":     "Ultra Sale",
+	"ultra_upgrade": "Ultra Sale",
+}
+
+// periodicalName looks up an event or sale type, falling back to the type itself in title case
+func periodicalName(names map[string]string, kind string) string {
+	if name, ok := names[kind]; ok {
+		return name
+	}
+
+	words := strings.FieldsFunc(kind, func(r rune) bool { return r == '-' || r == '_' })
+	for i, word := range words {
+		words[i] = titleCase(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// eventDetail is what an event gives players. The game's own message is used when it sends one, otherwise the
+// multiplier is spelled out: discount events carry the final price as their multiplier, e.g. 0.3 for 70% off.
+func eventDetail(event *Event) string {
+	if message := strings.TrimSpace(event.GetMessage()); message != "" {
+		return message
+	}
+
+	multiplier := event.GetMultiplier()
+	switch {
+	case multiplier <= 0:
+		return ""
+	case strings.HasSuffix(event.GetEventType(), "-sale"):
+		return fmt.Sprintf("%g%% off", math.Round((1-multiplier)*100))
+	default:
+		return fmt.Sprintf("%gx", multiplier)
+	}
+}
+
+// Announcements lists the events and sales that are still running, with when each ends worked out from now
+func Announcements(periodicals *Periodicals, now time.Time) []Announcement {
+	endsAt := func(seconds float64) time.Time {
+		return now.Add(time.Duration(math.Round(seconds)) * time.Second)
+	}
+
+	var announcements []Announcement
+	for _, event := range periodicals.GetEvents().GetEvents() {
+		if event.GetSecondsRemaining() <= 0 {
+			continue
+		}
+		announcements = append(announcements, Announcement{
+			Identifier: "event:" + event.GetId(),
+			Title:      periodicalName(eventNames, event.GetEventType()),
+			Detail:     eventDetail(event),
+			EndsAt:     endsAt(event.GetSecondsRemaining()),
+		})
+	}
+
+	for _, sale := range periodicals.GetSales().GetSales() {
+		if sale.GetSecondsRemaining() <= 0 {
+			continue
+		}
+		detail := ""
+		if sale.GetSaleAmount() != "" {
+			detail = fmt.Sprintf("%s off", sale.GetSaleAmount())
+		}
+		// sales are identified by their type when the game leaves out the ID
+		id := sale.GetId()
+		if id == "" {
+			id = sale.GetSaleType()
+		}
+		announcements = append(announcements, Announcement{
+			Identifier: "sale:" + id,
+			Title:      periodicalName(saleNames, sale.GetSaleType()),
+			Detail:     detail,
+			EndsAt:     endsAt(sale.GetSecondsRemaining()),
+		})
+	}
+
+	return announcements
+}
+
+// BuildAnnouncementsEmbed renders newly started events and sales with when each one ends
+func BuildAnnouncementsEmbed(announcements []Announcement) *discordgo.MessageEmbed {
+	embedFields := make([]*discordgo.MessageEmbedField, 0, len(announcements))
+	for _, announcement := range announcements {
+		if len(embedFields) == maxEmbedFields {
+			break
+		}
+
+		var lines []string
+		if announcement.Detail != "" {
+			lines = append(lines, announcement.Detail)
+		}
+		lines = append(lines, fmt.Sprintf("Ends <t:%d:R>, <t:%d:f>", announcement.EndsAt.Unix(), announcement.EndsAt.Unix()))
+
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Name:   announcement.Title,
+			Value:  strings.Join(lines, "\n"),
+			Inline: false,
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:      discordgo.EmbedTypeRich,
+		Title:     "New Events and Sales",
+		Timestamp: time.Now().Format(time.RFC3339),
+		Color:     0x8700C3, // button purple
+		Fields:    embedFields,
+	}
+}
@@ -0,0 +1,47 @@
+package api_test
+
+import (
+	"egg/api"
+	"egg/api/apitest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnouncements(t *testing.T) {
+	now := time.Unix(1646200000, 0)
+
+	announcements := api.Announcements(apitest.Periodicals(), now)
+	// the drone boost has already ended
+	require.Equal(t, []api.Announcement{
+		{Identifier: "event:e4c8", Title: "Research Sale", Detail: "70% off", EndsAt: now.Add(2 * time.Hour)},
+		{Identifier: "event:a1f2", Title: "Earnings Boost", Detail: "2x EARNINGS", EndsAt: now.Add(24 * time.Hour)},
+		{Identifier: "sale:gametime-piggy-sale", Title: "Piggy Bank Break", Detail: "40% off", EndsAt: now.Add(time.Hour)},
+	}, announcements)
+
+	t.Run("unknown types", func(t *testing.T) {
+		announcements := api.Announcements(&api.Periodicals{
+			Events: &api.Periodicals_Events{Events: []*api.Event{
+				{Id: "x", EventType: "shiny-new-boost", Multiplier: 3, SecondsRemaining: 60},
+			}},
+			Sales: &api.Periodicals_Sales{Sales: []*api.Sale{
+				{SaleType: "mystery_box", SecondsRemaining: 60},
+			}},
+		}, now)
+		require.Len(t, announcements, 2)
+		require.Equal(t, "Shiny New Boost", announcements[0].Title)
+		require.Equal(t, "3x", announcements[0].Detail)
+		require.Equal(t, "sale:mystery_box", announcements[1].Identifier)
+		require.Equal(t, "Mystery Box", announcements[1].Title)
+		require.Empty(t, announcements[1].Detail)
+	})
+}
+
+func TestBuildAnnouncementsEmbed(t *testing.T) {
+	embed := api.BuildAnnouncementsEmbed(api.Announcements(apitest.Periodicals(), time.Unix(1646200000, 0)))
+	require.Equal(t, "New Events and Sales", embed.Title)
+	require.Len(t, embed.Fields, 3)
+	require.Equal(t, "Research Sale", embed.Fields[0].Name)
+	require.Equal(t, "70% off\nEnds <t:1646207200:R>, <t:1646207200:f>", embed.Fields[0].Value)
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"egg/datastore"
+	"egg/format"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// GrowthPeriods are the windows a user's progress can be compared over
+var GrowthPeriods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// Growth is how much a user's progress changed between their oldest and newest snapshots in a period
+type Growth struct {
+	User          datastore.User
+	Since         time.Time
+	SoulEggs      float64
+	ProphecyEggs  int32
+	EarningsBonus float64
+	// EBPercent is the earnings bonus gained relative to where the user started the period
+	EBPercent float64
+}
+
+// GetGrowth compares the newest and oldest snapshots taken at or after since of every user registered in a guild.
+// Users without a snapshot in that window are left out.
+func GetGrowth(ctx context.Context, store datastore.Database, guildID string, since time.Time) ([]Growth, error) {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	users, err := tx.GetGuildUsers(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := tx.GetGuildSnapshotRange(guildID, since)
+	if err != nil {
+		return nil, err
+	}
+	byUser := snapshots.ByEggIncID()
+
+	growth := make([]Growth, 0)
+	for _, user := range users {
+		history, ok := byUser[user.EggIncID]
+		if !ok {
+			continue
+		}
+		oldest, newest := history[0], history[len(history)-1]
+
+		startEB, _ := calculateEB(snapshotProgress(oldest))
+		currentEB, _ := calculateEB(snapshotProgress(newest))
+
+		var ebPercent float64
+		if startEB > 0 {
+			ebPercent = (currentEB - startEB) / startEB * 100
+		}
+
+		growth = append(growth, Growth{
+			User:          user,
+			Since:         oldest.TakenAt,
+			SoulEggs:      newest.SoulEggs - oldest.SoulEggs,
+			ProphecyEggs:  newest.ProphecyEggs - oldest.ProphecyEggs,
+			EarningsBonus: currentEB - startEB,
+			EBPercent:     ebPercent,
+		})
+	}
+
+	return growth, nil
+}
+
+// snapshotProgress is the progress a snapshot recorded, in the shape calculateEB takes
+func snapshotProgress(snapshot datastore.UserSnapshot) datastore.User {
+	return datastore.User{
+		SoulFood:      snapshot.SoulFood,
+		ProphecyBonus: snapshot.ProphecyBonus,
+		SoulEggs:      snapshot.SoulEggs,
+		ProphecyEggs:  snapshot.ProphecyEggs,
+	}
+}
+
+// PruneSnapshots removes snapshots taken more than retention before now. A retention of zero keeps every snapshot.
+func PruneSnapshots(ctx context.Context, store datastore.Database, now time.Time, retention time.Duration) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.DeleteSnapshotsBefore(now.Add(-retention))
+	return err
+}
+
+// BuildGainersLeaderboard ranks a guild's users by how much their earnings bonus grew over a period, e.g. "week"
+func BuildGainersLeaderboard(ctx context.Context, store datastore.Database, guildID, period string, names NameResolver, numbers format.Options) (*discordgo.MessageEmbed, error) {
+	window, ok := GrowthPeriods[period]
+	if !ok {
+		return &discordgo.MessageEmbed{}, errors.New(fmt.Sprintf("'%s' isn't a period I know about", period))
+	}
+
+	growth, err := GetGrowth(ctx, store, guildID, time.Now().Add(-window))
+	if err != nil {
+		return &discordgo.MessageEmbed{}, err
+	}
+
+	sort.Slice(growth, func(i, j int) bool {
+		return growth[i].EBPercent > growth[j].EBPercent
+	})
+
+	embedFields := make([]*discordgo.MessageEmbedField, 0)
+	for i, gain := range growth {
+		if i == maxEmbedFields {
+			break
+		}
+
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Name: fmt.Sprintf("%d. %s %+.2f%% EB", i+1, names.resolve(gain.User), gain.EBPercent),
+			Value: fmt.Sprintf("+%s EB | +%s soul eggs | +%d prophecy eggs",
+				numbers.Number(gain.EarningsBonus), numbers.Number(gain.SoulEggs), gain.ProphecyEggs),
+			Inline: false,
+		})
+	}
+
+	description := ""
+	if len(embedFields) == 0 {
+		description = "Nobody has any history for this period yet"
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       fmt.Sprintf("Biggest Gainers This %s", titleCase(period)),
+		Description: description,
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Color:       0x8700C3, // button purple
+		Fields:      embedFields,
+	}, nil
+}
+
+func titleCase(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"egg/datastore"
+	"egg/format"
+	"fmt"
+)
+
+// DefaultMetric is what leaderboards rank by when no metric is asked for
+const DefaultMetric = "se"
+
+// Metric is something registered users can be ranked by on a leaderboard
+type Metric struct {
+	// Title names the leaderboard, e.g. "Soul Egg Leaderboard"
+	Title string
+	// Choice is how the metric is offered in the /board command
+	Choice string
+	// Value is what users are ranked by, highest first
+	Value func(user datastore.User) float64
+	// Headline is shown next to the user's name
+	Headline func(user datastore.User, numbers format.Options) string
+	// Detail is shown under the user's name, their farmer rank when not set
+	Detail func(user datastore.User, numbers format.Options) string
+}
+
+// MetricNames is every metric in the order they're offered
+var MetricNames = []string{"se", "eb", "pe", "golden_eggs", "prestiges", "drones", "elite_drones", "boosts", "piggy", "trophies"}
+
+// Metrics are the leaderboards on offer, keyed by name
+var Metrics = map[string]Metric{
+	"se": {
+		Title:  "Soul Egg Leaderboard",
+		Choice: "Soul eggs",
+		Value:  func(user datastore.User) float64 { return user.SoulEggs },
+		Headline: func(user datastore.User, numbers format.Options) string {
+			eb, _ := calculateEB(user)
+			return numbers.Number(eb)
+		},
+		Detail: func(user datastore.User, numbers format.Options) string {
+			return fmt.Sprintf("%s soul eggs | %s", numbers.Number(user.SoulEggs), GetRank(user).Name)
+		},
+	},
+	"eb": {
+		Title:  "Earnings Bonus Leaderboard",
+		Choice: "Earnings bonus",
+		Value: func(user datastore.User) float64 {
+			eb, _ := calculateEB(user)
+			return eb
+		},
+		Headline: func(user datastore.User, numbers format.Options) string {
+			eb, _ := calculateEB(user)
+			return numbers.Number(eb) + "%"
+		},
+	},
+	"pe": {
+		Title:  "Prophecy Egg Leaderboard",
+		Choice: "Prophecy eggs",
+		Value:  func(user datastore.User) float64 { return float64(user.ProphecyEggs) },
+		Headline: func(user datastore.User, _ format.Options) string {
+			return fmt.Sprintf("%d prophecy eggs", user.ProphecyEggs)
+		},
+	},
+	"golden_eggs": {
+		Title:  "Golden Egg Leaderboard",
+		Choice: "Lifetime golden eggs",
+		Value:  func(user datastore.User) float64 { return float64(user.GoldenEggs) },
+		Headline: func(user datastore.User, numbers format.Options) string {
+			return numbers.Number(float64(user.GoldenEggs)) + " golden eggs"
+		},
+	},
+	"prestiges": {
+		Title:    "Prestige Leaderboard",
+		Choice:   "Prestiges",
+		Value:    func(user datastore.User) float64 { return float64(user.Prestiges) },
+		Headline: func(user datastore.User, _ format.Options) string { return fmt.Sprintf("%d prestiges", user.Prestiges) },
+	},
+	"drones": {
+		Title:  "Drone Takedown Leaderboard",
+		Choice: "Drone takedowns",
+		Value:  func(user datastore.User) float64 { return float64(user.DroneTakedowns) },
+		Headline: func(user datastore.User, _ format.Options) string {
+			return fmt.Sprintf("%d drones", user.DroneTakedowns)
+		},
+	},
+	"elite_drones": {
+		Title:  "Elite Drone Takedown Leaderboard",
+		Choice: "Elite drone takedowns",
+		Value:  func(user datastore.User) float64 { return float64(user.EliteDroneTakedowns) },
+		Headline: func(user datastore.User, _ format.Options) string {
+			return fmt.Sprintf("%d elite drones", user.EliteDroneTakedowns)
+		},
+	},
+	"boosts": {
+		Title:    "Boosts Used Leaderboard",
+		Choice:   "Boosts used",
+		Value:    func(user datastore.User) float64 { return float64(user.BoostsUsed) },
+		Headline: func(user datastore.User, _ format.Options) string { return fmt.Sprintf("%d boosts", user.BoostsUsed) },
+	},
+	"piggy": {
+		Title:  "Piggy Bank Leaderboard",
+		Choice: "Piggy bank level",
+		Value:  func(user datastore.User) float64 { return float64(user.PiggyLevel) },
+		Headline: func(user datastore.User, _ format.Options) string {
+			return fmt.Sprintf("piggy level %d", user.PiggyLevel)
+		},
+	},
+	"trophies": {
+		Title:  "Trophy Leaderboard",
+		Choice: "Farm trophies",
+		Value:  func(user datastore.User) float64 { return float64(trophyScore(user)) },
+		Headline: func(user datastore.User, _ format.Options) string {
+			return fmt.Sprintf("%d trophy levels", trophyScore(user))
+		},
+		Detail: func(user datastore.User, _ format.Options) string {
+			return fmt.Sprintf("%d diamond trophies | %s", diamondTrophies(user), GetRank(user).Name)
+		},
+	},
+}
+
+// trophyScore adds up the trophy levels of every farm, from 1 for bronze to 5 for diamond
+func trophyScore(user datastore.User) int {
+	var score int
+	for _, level := range user.Trophies() {
+		score += int(level)
+	}
+	return score
+}
+
+func diamondTrophies(user datastore.User) int {
+	var diamonds int
+	for _, level := range user.Trophies() {
+		if TrophyType(level) == TrophyType_DIAMOND {
+			diamonds++
+		}
+	}
+	return diamonds
+}
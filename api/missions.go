@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"egg/datastore"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// shipNames are the names the game shows for each spaceship
+var shipNames = map[MissionInfo_Spaceship]string{
+	MissionInfo_CHICKEN_ONE:         "Chicken One",
+	MissionInfo_CHICKEN_NINE:        "Chicken Nine",
+	MissionInfo_CHICKEN_HEAVY:       "Chicken Heavy",
+	MissionInfo_BCR:                 "BCR",
+	MissionInfo_MILLENIUM_CHICKEN:   "Quintillion Chicken",
+	MissionInfo_CORELLIHEN_CORVETTE: "Cornish-Hen Corvette",
+	MissionInfo_GALEGGTICA:          "Galeggtica",
+	MissionInfo_CHICKFIANT:          "Defihent",
+	MissionInfo_VOYEGGER:            "Voyegger",
+	MissionInfo_HENERPRISE:          "Henerprise",
+}
+
+// durationNames are the names the game shows for each length of mission
+var durationNames = map[MissionInfo_DurationType]string{
+	MissionInfo_SHORT:    "Short",
+	MissionInfo_LONG:     "Standard",
+	MissionInfo_EPIC:     "Extended",
+	MissionInfo_TUTORIAL: "Tutorial",
+}
+
+// ShipName is the name the game shows for a spaceship
+func ShipName(ship MissionInfo_Spaceship) string {
+	if name, ok := shipNames[ship]; ok {
+		return name
+	}
+	return ship.String()
+}
+
+// DurationName is the name the game shows for a length of mission
+func DurationName(duration MissionInfo_DurationType) string {
+	if name, ok := durationNames[duration]; ok {
+		return name
+	}
+	return duration.String()
+}
+
+// inFlight reports whether a mission has launched and its ship hasn't been unloaded yet
+func inFlight(mission *MissionInfo) bool {
+	return mission.GetStatus() == MissionInfo_EXPLORING || mission.GetStatus() == MissionInfo_RETURNED
+}
+
+// missionReturnsAt is when a mission's ship lands. The derived start time is preferred since seconds_remaining is
+// only accurate as of when the backup was taken.
+func missionReturnsAt(mission *MissionInfo, backedUpAt time.Time) time.Time {
+	if mission.GetStartTimeDerived() > 0 && mission.GetDurationSeconds() > 0 {
+		sec, frac := math.Modf(mission.GetStartTimeDerived() + mission.GetDurationSeconds())
+		return time.Unix(int64(sec), int64(frac*1e9))
+	}
+	return backedUpAt.Add(time.Duration(mission.GetSecondsRemaining() * float64(time.Second)))
+}
+
+// MissionRecords is every mission in flight in a backup, ready to be stored so landings can be announced without
+// fetching the backup again. Ships that had already landed when the backup was taken are stored as announced, so
+// nobody hears about a landing from hours or days ago the first time their missions are saved.
+func MissionRecords(backup *FirstContact_Payload, backedUpAt time.Time) datastore.Missions {
+	var missions datastore.Missions
+	for _, mission := range backup.GetArtifactsDb().GetMissionInfos() {
+		if !inFlight(mission) || mission.GetIdentifier() == "" {
+			continue
+		}
+		returnsAt := missionReturnsAt(mission, backedUpAt)
+		missions = append(missions, datastore.Mission{
+			Identifier:   mission.GetIdentifier(),
+			EggIncID:     backup.GetEiUserId(),
+			Ship:         int32(mission.GetShip()),
+			DurationType: int32(mission.GetDurationType()),
+			ReturnsAt:    returnsAt,
+			Notified:     mission.GetStatus() == MissionInfo_RETURNED || !returnsAt.After(backedUpAt),
+		})
+	}
+	return missions
+}
+
+// SaveMissions stores the missions in flight in a freshly fetched backup
+func SaveMissions(ctx context.Context, store datastore.Database, backup *FirstContact_Payload) error {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.SaveMissions(MissionRecords(backup, time.Now()))
+	return err
+}
+
+// BuildMissionsEmbed shows each ship a backup has in flight and when it lands
+func BuildMissionsEmbed(backup *FirstContact_Payload, now time.Time) *discordgo.MessageEmbed {
+	var embedFields []*discordgo.MessageEmbedField
+	for _, mission := range backup.GetArtifactsDb().GetMissionInfos() {
+		if !inFlight(mission) {
+			continue
+		}
+		if len(embedFields) == maxEmbedFields {
+			break
+		}
+
+		returnsAt := missionReturnsAt(mission, now)
+		value := fmt.Sprintf("Lands <t:%d:R>, <t:%d:t>", returnsAt.Unix(), returnsAt.Unix())
+		if mission.GetStatus() == MissionInfo_RETURNED || !returnsAt.After(now) {
+			value = fmt.Sprintf("Landed <t:%d:R> and is waiting to be unloaded", returnsAt.Unix())
+		}
+		if mission.GetCapacity() > 0 {
+			value += fmt.Sprintf("\nCapacity: %d", mission.GetCapacity())
+		}
+
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("%s | %s", ShipName(mission.GetShip()), DurationName(mission.GetDurationType())),
+			Value:  value,
+			Inline: false,
+		})
+	}
+
+	description := ""
+	if len(embedFields) == 0 {
+		description = "No ships in flight"
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       fmt.Sprintf("%s's Ships", backup.GetUserName()),
+		Description: description,
+		Timestamp:   now.Format(time.RFC3339),
+		Color:       0x8700C3, // button purple
+		Fields:      embedFields,
+	}
+}
@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"egg/api"
+	"egg/api/apitest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissionRecords(t *testing.T) {
+	backup := apitest.Backup("EI1111", "akroh")
+	backedUpAt := time.Unix(1646200000, 0)
+
+	missions := api.MissionRecords(backup, backedUpAt)
+	require.Len(t, missions, 2)
+	require.Equal(t, "mission-henerprise", missions[0].Identifier)
+	require.Equal(t, "EI1111", missions[0].EggIncID)
+	require.Equal(t, int32(api.MissionInfo_HENERPRISE), missions[0].Ship)
+	// the derived start time wins over seconds_remaining, which goes stale
+	require.Equal(t, time.Unix(1646136000+172800, 0), missions[0].ReturnsAt)
+	require.Equal(t, time.Unix(1646128800+3600, 0), missions[1].ReturnsAt)
+	// the Chicken Heavy landed before the backup was taken, so there's nothing to announce
+	require.False(t, missions[0].Notified)
+	require.True(t, missions[1].Notified)
+
+	t.Run("landed while exploring", func(t *testing.T) {
+		backup := &api.FirstContact_Payload{ArtifactsDb: &api.ArtifactsDB{MissionInfos: []*api.MissionInfo{
+			{Identifier: "m", Status: api.MissionInfo_EXPLORING, StartTimeDerived: 1646000000, DurationSeconds: 3600},
+		}}}
+		missions := api.MissionRecords(backup, backedUpAt)
+		require.True(t, missions[0].Notified)
+	})
+
+	t.Run("without a start time", func(t *testing.T) {
+		backup := &api.FirstContact_Payload{ArtifactsDb: &api.ArtifactsDB{MissionInfos: []*api.MissionInfo{
+			{Identifier: "m", Status: api.MissionInfo_EXPLORING, SecondsRemaining: 90},
+		}}}
+		missions := api.MissionRecords(backup, backedUpAt)
+		require.Equal(t, backedUpAt.Add(90*time.Second), missions[0].ReturnsAt)
+		require.False(t, missions[0].Notified)
+	})
+}
+
+func TestBuildMissionsEmbed(t *testing.T) {
+	embed := api.BuildMissionsEmbed(apitest.Backup("EI1111", "akroh"), time.Unix(1646200000, 0))
+	require.Equal(t, "akroh's Ships", embed.Title)
+	require.Len(t, embed.Fields, 2)
+
+	require.Equal(t, "Henerprise | Extended", embed.Fields[0].Name)
+	require.Equal(t, "Lands <t:1646308800:R>, <t:1646308800:t>\nCapacity: 50", embed.Fields[0].Value)
+	require.Equal(t, "Chicken Heavy | Short", embed.Fields[1].Name)
+	require.Contains(t, embed.Fields[1].Value, "waiting to be unloaded")
+
+	embed = api.BuildMissionsEmbed(&api.FirstContact_Payload{UserName: "new"}, time.Now())
+	require.Equal(t, "No ships in flight", embed.Description)
+}
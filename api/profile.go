@@ -0,0 +1,69 @@
+package api
+
+import (
+	"egg/datastore"
+	"egg/format"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// BuildProfileEmbed shows everything stored about each of a Discord user's registered accounts
+func BuildProfileEmbed(name string, users datastore.Users, numbers format.Options) *discordgo.MessageEmbed {
+	embedFields := make([]*discordgo.MessageEmbedField, 0, len(users))
+	for _, user := range users {
+		if len(embedFields) == maxEmbedFields {
+			break
+		}
+
+		rank := GetRank(user)
+		lines := []string{
+			fmt.Sprintf("**SE:** %s | **PE:** %d | **EB:** %s%%", numbers.Number(user.SoulEggs), user.ProphecyEggs, numbers.Number(rank.EB)),
+			fmt.Sprintf("**Soul food:** %d | **Prophecy bonus:** %d", user.SoulFood, user.ProphecyBonus),
+			fmt.Sprintf("**Prestiges:** %d | **Golden eggs:** %s | **Piggy level:** %d", user.Prestiges, numbers.Number(float64(user.GoldenEggs)), user.PiggyLevel),
+			fmt.Sprintf("**Trophies:** %s", trophySummary(user)),
+			fmt.Sprintf("**Last refreshed:** <t:%d:R>", user.UpdatedAt.Unix()),
+		}
+
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("%s | %s", user.GameAccountName, rank.Name),
+			Value:  strings.Join(lines, "\n"),
+			Inline: false,
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:      discordgo.EmbedTypeRich,
+		Title:     fmt.Sprintf("%s's Profile", name),
+		Timestamp: time.Now().Format(time.RFC3339),
+		Color:     0x8700C3, // button purple
+		Fields:    embedFields,
+	}
+}
+
+// trophySummary lists the eggs a user has earned each trophy on, best trophy first
+func trophySummary(user datastore.User) string {
+	byTrophy := make(map[TrophyType][]string)
+	// farm trophy levels start at the first real egg, edible
+	for i, level := range user.Trophies() {
+		trophy := TrophyType(level)
+		if trophy == TrophyType_NO_TROPHY {
+			continue
+		}
+		byTrophy[trophy] = append(byTrophy[trophy], eggName(EggType(i+1)))
+	}
+
+	var groups []string
+	for trophy := TrophyType_DIAMOND; trophy > TrophyType_NO_TROPHY; trophy-- {
+		if eggs, ok := byTrophy[trophy]; ok {
+			groups = append(groups, fmt.Sprintf("%s: %s", titleCase(strings.ToLower(trophy.String())), strings.Join(eggs, ", ")))
+		}
+	}
+
+	if len(groups) == 0 {
+		return "none yet"
+	}
+	return strings.Join(groups, " | ")
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"egg/datastore"
+	"egg/format"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildProfileEmbed(t *testing.T) {
+	updatedAt := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	users := datastore.Users{
+		{
+			GameAccountName: "akroh",
+			SoulEggs:        1e18,
+			ProphecyEggs:    120,
+			SoulFood:        140,
+			ProphecyBonus:   5,
+			Prestiges:       42,
+			GoldenEggs:      250000,
+			PiggyLevel:      8,
+			TrophyLevels:    "5,5,4,0,3",
+			UpdatedAt:       updatedAt,
+		},
+		{GameAccountName: "alt"},
+	}
+
+	embed := BuildProfileEmbed("krohmag", users, format.Default)
+	require.Equal(t, "krohmag's Profile", embed.Title)
+	require.Len(t, embed.Fields, 2)
+
+	main := embed.Fields[0]
+	require.True(t, strings.HasPrefix(main.Name, "akroh | "), main.Name)
+	require.Contains(t, main.Value, "**PE:** 120")
+	require.Contains(t, main.Value, "**Soul food:** 140 | **Prophecy bonus:** 5")
+	require.Contains(t, main.Value, "**Prestiges:** 42 | **Golden eggs:** 250.000k | **Piggy level:** 8")
+	require.Contains(t, main.Value, "**Trophies:** Diamond: Edible, Superfood | Platinum: Medical | Gold: Super Material")
+	require.Contains(t, main.Value, "<t:1646136000:R>")
+
+	require.Contains(t, embed.Fields[1].Value, "**Trophies:** none yet")
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"egg/datastore"
+	"egg/format"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// rankTiers are the prefixes of the in-game farmer ranks. Every tier spans three orders of magnitude
+// of earnings bonus, one for each of its I, II and III ranks.
+var rankTiers = []string{
+	"", "Kilo", "Mega", "Giga", "Tera", "Peta", "Exa", "Zetta", "Yotta",
+	"Xenna", "Wecca", "Venda", "Uada", "Treida", "Quada", "Penda", "Exeda",
+}
+
+// FarmerRanks is every farmer rank from lowest to highest
+var FarmerRanks = farmerRanks()
+
+func farmerRanks() []string {
+	ranks := make([]string, 0, len(rankTiers)*3+1)
+	for _, tier := range rankTiers {
+		name := "Farmer"
+		if tier != "" {
+			name = tier + "farmer"
+		}
+		for _, numeral := range []string{"I", "II", "III"} {
+			ranks = append(ranks, fmt.Sprintf("%s %s", name, numeral))
+		}
+	}
+	return append(ranks, "Infinifarmer")
+}
+
+// Rank is where a user sits on the farmer rank ladder and what it takes to climb to the next rung
+type Rank struct {
+	Name string
+	// Level is the rank's position in FarmerRanks
+	Level int
+	// EB is the user's earnings bonus in percent
+	EB float64
+
+	// NextName is the rank above this one, or empty at the top
+	NextName string
+	// NextEB is the earnings bonus in percent the next rank starts at
+	NextEB float64
+	// SoulEggsForNext is how many soul eggs reach NextEB at the user's current prophecy eggs and epic research
+	SoulEggsForNext float64
+	// SoulEggsToNext is how many more soul eggs than they have now that is
+	SoulEggsToNext float64
+}
+
+// RankForEB maps an earnings bonus in percent to its farmer rank. Farmer I covers everything below 10%,
+// and each rank after that starts at the next power of ten. NaN counts as Farmer I and +Inf as the top rank.
+func RankForEB(eb float64) (string, int) {
+	top := len(FarmerRanks) - 1
+
+	level := 0
+	switch {
+	case math.IsInf(eb, 1):
+		level = top
+	case eb >= 10:
+		// converting a float past the int range isn't defined, so clamp before converting
+		level = int(math.Min(math.Floor(math.Log10(eb)), float64(top)))
+	}
+	if level < 0 {
+		level = 0
+	}
+	return FarmerRanks[level], level
+}
+
+// GetRank works out a user's farmer rank along with what they need for the next one
+func GetRank(user datastore.User) Rank {
+	eb, _ := calculateEB(user)
+	name, level := RankForEB(eb)
+
+	rank := Rank{
+		Name:  name,
+		Level: level,
+		EB:    eb,
+	}
+	if level == len(FarmerRanks)-1 {
+		return rank
+	}
+
+	rank.NextName = FarmerRanks[level+1]
+	rank.NextEB = math.Pow(10, float64(level+1))
+	if perSoulEgg := ebPerSoulEgg(user); perSoulEgg > 0 {
+		rank.SoulEggsForNext = rank.NextEB / perSoulEgg
+		rank.SoulEggsToNext = math.Max(rank.SoulEggsForNext-user.SoulEggs, 0)
+	}
+
+	return rank
+}
+
+// BuildRankEmbed shows the farmer rank of each of a Discord user's registered accounts
+func BuildRankEmbed(users datastore.Users, numbers format.Options) *discordgo.MessageEmbed {
+	embedFields := make([]*discordgo.MessageEmbedField, 0, len(users))
+	for _, user := range users {
+		rank := GetRank(user)
+
+		value := fmt.Sprintf("%s%% earnings bonus | %s soul eggs", numbers.Number(rank.EB), numbers.Number(user.SoulEggs))
+		if rank.NextName != "" {
+			value += fmt.Sprintf("\n%s at %s%%, which takes %s soul eggs (%s more)",
+				rank.NextName, numbers.Number(rank.NextEB), numbers.Number(rank.SoulEggsForNext), numbers.Number(rank.SoulEggsToNext))
+		} else {
+			value += "\nThere's nowhere left to climb"
+		}
+
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("%s: %s", user.GameAccountName, rank.Name),
+			Value:  value,
+			Inline: false,
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:      discordgo.EmbedTypeRich,
+		Title:     "Farmer Rank",
+		Timestamp: time.Now().Format(time.RFC3339),
+		Color:     0x8700C3, // button purple
+		Fields:    embedFields,
+	}
+}
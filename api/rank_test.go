@@ -0,0 +1,58 @@
+package api
+
+import (
+	"egg/datastore"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankForEB(t *testing.T) {
+	tests := []struct {
+		eb    float64
+		rank  string
+		level int
+	}{
+		{eb: 0, rank: "Farmer I", level: 0},
+		{eb: 9.99, rank: "Farmer I", level: 0},
+		{eb: 10, rank: "Farmer II", level: 1},
+		{eb: 999, rank: "Farmer III", level: 2},
+		{eb: 1e3, rank: "Kilofarmer I", level: 3},
+		{eb: 5e7, rank: "Megafarmer II", level: 7},
+		{eb: 2e21, rank: "Zettafarmer I", level: 21},
+		{eb: 1e50, rank: "Exedafarmer III", level: 50},
+		{eb: 1e51, rank: "Infinifarmer", level: 51},
+		{eb: 1e90, rank: "Infinifarmer", level: 51},
+		{eb: math.MaxFloat64, rank: "Infinifarmer", level: 51},
+		{eb: math.Inf(1), rank: "Infinifarmer", level: 51},
+		{eb: math.Inf(-1), rank: "Farmer I", level: 0},
+		{eb: math.NaN(), rank: "Farmer I", level: 0},
+		{eb: -5, rank: "Farmer I", level: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.eb), func(t *testing.T) {
+			rank, level := RankForEB(tt.eb)
+			require.Equal(t, tt.rank, rank)
+			require.Equal(t, tt.level, level)
+		})
+	}
+}
+
+func TestGetRank(t *testing.T) {
+	// 1e18 SE at 10% each is 1e19% EB, which is Exafarmer II
+	user := datastore.User{SoulEggs: 1e18}
+	rank := GetRank(user)
+	require.Equal(t, "Exafarmer II", rank.Name)
+	require.Equal(t, "Exafarmer III", rank.NextName)
+	require.InDelta(t, 1e20, rank.NextEB, 1)
+	require.InDelta(t, 1e19, rank.SoulEggsForNext, 1e6)
+	require.InDelta(t, 9e18, rank.SoulEggsToNext, 1e6)
+
+	top := GetRank(datastore.User{SoulEggs: 1e60})
+	require.Equal(t, "Infinifarmer", top.Name)
+	require.Empty(t, top.NextName)
+	require.Zero(t, top.SoulEggsToNext)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ShipLevel is how far an account has levelled up one of its spaceships
+type ShipLevel struct {
+	Ship MissionInfo_Spaceship
+	// Launches counts every mission the ship has flown, including those still in flight
+	Launches uint32
+	Level    int
+	MaxLevel int
+	// LaunchesToNext is how many more launches reach the next level, zero once at the max level
+	LaunchesToNext uint32
+}
+
+// shipLaunches counts how many times a backup has launched each ship, from its finished missions along with those
+// still in flight
+func shipLaunches(backup *FirstContact_Payload) map[MissionInfo_Spaceship]uint32 {
+	launches := make(map[MissionInfo_Spaceship]uint32)
+	for _, mission := range backup.GetArtifactsDb().GetMissionArchive() {
+		launches[mission.GetShip()]++
+	}
+	for _, mission := range backup.GetArtifactsDb().GetMissionInfos() {
+		if mission.GetStatus() >= MissionInfo_EXPLORING {
+			launches[mission.GetShip()]++
+		}
+	}
+	return launches
+}
+
+// GetShipLevel works out a ship's level from how many times it has launched. Each entry of level_mission_requirements
+// is how many more launches the next level takes.
+func GetShipLevel(params *ArtifactsConfigurationResponse_MissionParameters, launches uint32) ShipLevel {
+	requirements := params.GetLevelMissionRequirements()
+	level := ShipLevel{Ship: params.GetShip(), Launches: launches, MaxLevel: len(requirements)}
+
+	var needed uint32
+	for _, requirement := range requirements {
+		needed += requirement
+		if launches < needed {
+			level.LaunchesToNext = needed - launches
+			break
+		}
+		level.Level++
+	}
+
+	return level
+}
+
+// shipDurations describes each length of mission a ship flies at a level: how long it takes, how many artifacts it
+// brings back and their quality
+func shipDurations(params *ArtifactsConfigurationResponse_MissionParameters, level int) []string {
+	var lines []string
+	for _, duration := range params.GetDurations() {
+		if duration.GetDurationType() == MissionInfo_TUTORIAL {
+			continue
+		}
+		capacity := duration.GetCapacity() + uint32(level)*duration.GetLevelCapacityBump()
+		quality := duration.GetQuality() + float32(level)*duration.GetLevelQualityBump()
+		lines = append(lines, fmt.Sprintf("**%s:** %s | capacity %d | quality %.2f",
+			DurationName(duration.GetDurationType()), humanDuration(duration.GetSeconds()), capacity, quality))
+	}
+	return lines
+}
+
+// BuildShipsEmbed shows each of an account's spaceships with its level, capacity and duration per mission length and
+// how many launches remain to the next level
+func BuildShipsEmbed(backup *FirstContact_Payload, artifactsConfig *ArtifactsConfigurationResponse) *discordgo.MessageEmbed {
+	launches := shipLaunches(backup)
+
+	var embedFields []*discordgo.MessageEmbedField
+	for _, params := range artifactsConfig.GetMissionParameters() {
+		if len(embedFields) == maxEmbedFields {
+			break
+		}
+
+		level := GetShipLevel(params, launches[params.GetShip()])
+		progress := fmt.Sprintf("%d launches | max level", level.Launches)
+		switch {
+		case level.Launches == 0:
+			progress = "Not launched yet"
+		case level.LaunchesToNext > 0:
+			progress = fmt.Sprintf("%d launches | %d more to level %d", level.Launches, level.LaunchesToNext, level.Level+1)
+		}
+
+		lines := append(shipDurations(params, level.Level), progress)
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("%s | Level %d of %d", ShipName(params.GetShip()), level.Level, level.MaxLevel),
+			Value:  strings.Join(lines, "\n"),
+			Inline: false,
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:      discordgo.EmbedTypeRich,
+		Title:     fmt.Sprintf("%s's Fleet", backup.GetUserName()),
+		Timestamp: time.Now().Format(time.RFC3339),
+		Color:     0x8700C3, // button purple
+		Fields:    embedFields,
+	}
+}
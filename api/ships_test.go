@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	"context"
+	"egg/api"
+	"egg/api/apitest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetShipLevel(t *testing.T) {
+	params := &api.ArtifactsConfigurationResponse_MissionParameters{LevelMissionRequirements: []uint32{2, 3, 5}}
+
+	tests := []struct {
+		launches uint32
+		level    int
+		toNext   uint32
+	}{
+		{launches: 0, level: 0, toNext: 2},
+		{launches: 2, level: 1, toNext: 3},
+		{launches: 4, level: 1, toNext: 1},
+		{launches: 10, level: 3, toNext: 0},
+		{launches: 99, level: 3, toNext: 0},
+	}
+
+	for _, tt := range tests {
+		level := api.GetShipLevel(params, tt.launches)
+		require.Equal(t, tt.level, level.Level, tt.launches)
+		require.Equal(t, tt.toNext, level.LaunchesToNext, tt.launches)
+		require.Equal(t, 3, level.MaxLevel)
+	}
+}
+
+func TestBuildShipsEmbed(t *testing.T) {
+	embed := api.BuildShipsEmbed(apitest.Backup("EI1111", "akroh"), apitest.ArtifactsConfig())
+	require.Equal(t, "akroh's Fleet", embed.Title)
+	require.Len(t, embed.Fields, 3)
+
+	// one archived BCR mission, while the one being fueled hasn't launched
+	require.Equal(t, "BCR | Level 0 of 2", embed.Fields[0].Name)
+	require.Equal(t, "**Short:** 4h 0m | capacity 10 | quality 1.40\n1 launches | 3 more to level 1", embed.Fields[0].Value)
+
+	// three archived Henerprise missions and one in flight
+	require.Equal(t, "Henerprise | Level 1 of 3", embed.Fields[1].Name)
+	require.Equal(t, "**Short:** 2d 0h 0m | capacity 45 | quality 3.25\n**Extended:** 4d 0h 0m | capacity 55 | quality 4.25\n4 launches | 1 more to level 2", embed.Fields[1].Value)
+
+	require.Equal(t, "Not launched yet", embed.Fields[2].Value)
+}
+
+func TestClientArtifactsConfigCache(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	server.SetArtifactsConfig(apitest.ArtifactsConfig())
+
+	client := server.APIClient()
+	ctx := context.Background()
+
+	_, err := client.ArtifactsConfig(ctx)
+	require.NoError(t, err)
+	_, err = client.ArtifactsConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, server.Requests(apitest.PathArtifactsConfig))
+
+	t.Run("expired configs are fetched again", func(t *testing.T) {
+		client.ArtifactsConfigTTL = time.Nanosecond
+		defer func() { client.ArtifactsConfigTTL = api.DefaultArtifactsConfigTTL }()
+		time.Sleep(time.Millisecond)
+
+		_, err := client.ArtifactsConfig(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, server.Requests(apitest.PathArtifactsConfig))
+	})
+
+	t.Run("the last config is used when fetching fails", func(t *testing.T) {
+		client.ArtifactsConfigTTL = 0
+		defer func() { client.ArtifactsConfigTTL = api.DefaultArtifactsConfigTTL }()
+		server.SetFault(apitest.PathArtifactsConfig, apitest.Fault{StatusCode: 500})
+		defer server.ClearFaults()
+
+		artifactsConfig, err := client.ArtifactsConfig(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, artifactsConfig.GetMissionParameters())
+	})
+
+	t.Run("fetching fails without a config to fall back on", func(t *testing.T) {
+		server.SetFault(apitest.PathArtifactsConfig, apitest.Fault{StatusCode: 500})
+		defer server.ClearFaults()
+
+		_, err := server.APIClient().ArtifactsConfig(ctx)
+		require.Error(t, err)
+	})
+}
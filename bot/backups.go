@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"context"
+	"egg/api"
+	"egg/datastore"
+	"fmt"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxMessageEmbeds is the most embeds Discord allows on a single message
+	maxMessageEmbeds = 10
+	// maxBackupFetches is how many backups one command fetches from the Egg, Inc. API at once
+	maxBackupFetches = 4
+)
+
+// chosenUser is the Discord user picked with a command's "user" option, or the person interacting when none was given
+func chosenUser(i *discordgo.InteractionCreate) *discordgo.User {
+	user := interactionUser(i)
+	if option, ok := optionMap(i)["user"]; ok {
+		user = option.UserValue(nil)
+		if resolved := i.ApplicationCommandData().Resolved; resolved != nil && resolved.Users[user.ID] != nil {
+			user = resolved.Users[user.ID]
+		}
+	}
+	return user
+}
+
+// registeredUsers looks up every account a Discord user has registered, naming them when it's someone other than the
+// person interacting who hasn't registered
+func registeredUsers(ctx context.Context, store datastore.Database, i *discordgo.InteractionCreate, user *discordgo.User) (datastore.Users, error) {
+	users, err := api.GetDiscordUsers(ctx, store, user.ID, user.Username)
+	if errors.Is(err, api.ErrNotRegistered) && user.ID != interactionUser(i).ID {
+		err = errors.New(fmt.Sprintf("%s hasn't registered an Egg, Inc. user ID yet", user.Username))
+	}
+	return users, err
+}
+
+// fetchBackups fetches a fresh backup of registered accounts, one message's worth of embeds at most, for commands
+// that need more than the datastore keeps. Backups are fetched a few at a time and returned in the order of users.
+// Callers should defer their response first, as this easily takes longer than Discord waits for an answer.
+func fetchBackups(ctx context.Context, client *api.Client, users datastore.Users) ([]*api.FirstContact_Payload, error) {
+	if len(users) > maxMessageEmbeds {
+		users = users[:maxMessageEmbeds]
+	}
+
+	// the first failure cancels the fetches still waiting on the API
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var fetchErr error
+	backups := make([]*api.FirstContact_Payload, len(users))
+	sem := make(chan struct{}, maxBackupFetches)
+	for n, user := range users {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(n int, user datastore.User) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			backup, err := client.GetBackup(ctx, user.EggIncID)
+			if err != nil {
+				mu.Lock()
+				if fetchErr == nil {
+					fetchErr = err
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			backups[n] = backup
+		}(n, user)
+	}
+	wg.Wait()
+
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
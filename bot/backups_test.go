@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"context"
+	"egg/api/apitest"
+	"egg/datastore"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchBackups(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	var users datastore.Users
+	for n := 0; n < maxMessageEmbeds+2; n++ {
+		eggIncID := fmt.Sprintf("EI%04d", n)
+		server.SetBackup(apitest.Backup(eggIncID, fmt.Sprintf("farmer%d", n)))
+		users = append(users, datastore.User{EggIncID: eggIncID})
+	}
+
+	backups, err := fetchBackups(context.Background(), server.APIClient(), users)
+	require.NoError(t, err)
+	require.Len(t, backups, maxMessageEmbeds)
+	for n, backup := range backups {
+		require.Equal(t, users[n].EggIncID, backup.GetEiUserId())
+	}
+
+	t.Run("fetching fails", func(t *testing.T) {
+		server.SetFault(apitest.PathFirstContact, apitest.Fault{StatusCode: 500})
+		defer server.ClearFaults()
+
+		_, err := fetchBackups(context.Background(), server.APIClient(), users)
+		require.Error(t, err)
+	})
+}
@@ -5,12 +5,16 @@ import (
 	"egg/api"
 	"egg/config"
 	"egg/datastore"
+	"egg/format"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 var (
@@ -41,25 +45,249 @@ var (
 		},
 		{
 			Name:        "board",
-			Description: "Temporary command to generate the soul egg leaderboard",
+			Description: "Refresh the soul egg leaderboard and show where you are on any leaderboard",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "metric",
+					Description: "What to rank by. Defaults to soul eggs",
+					Required:    false,
+					Choices:     metricChoices(),
+				},
+			},
+		},
+		{
+			Name:        "setboard",
+			Description: "Admin only: choose the channel the soul egg leaderboard is posted in",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "The channel to post the leaderboard in",
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					Required:     true,
+				},
+			},
+		},
+		{
+			Name:        "gainers",
+			Description: "Show who grew their earnings bonus the most",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "period",
+					Description: "How far back to look",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Past day", Value: "day"},
+						{Name: "Past week", Value: "week"},
+						{Name: "Past month", Value: "month"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "coop",
+			Description: "Show the status of a coop",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "contract",
+					Description: "The contract ID, e.g. 'spring-2022'",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "code",
+					Description: "The coop code",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "contracts",
+			Description: "List the contracts currently on offer",
+		},
+		{
+			Name:        "rank",
+			Description: "Show your farmer rank and what it takes to reach the next one",
+		},
+		{
+			Name:        "eb",
+			Description: "Work out the earnings bonus and rank for any soul eggs, prophecy eggs and epic research",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "se",
+					Description: "Soul eggs, e.g. 1.5Q or 1.5e18",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "pe",
+					Description: "Prophecy eggs (0-1000)",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "soul_food",
+					Description: "Soul food epic research level (0-140). Defaults to your registered account's, or 140",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "prophecy_bonus",
+					Description: "Prophecy bonus epic research level (0-5). Defaults to your registered account's, or 5",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "profile",
+			Description: "Show the stats of every account someone has registered",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Whose profile to show. Defaults to yours",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "syncroles",
+			Description: "Admin only: sync members' farmer rank roles",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "dryrun",
+					Description: "Only show what would change. Defaults to true",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "artifacts",
+			Description: "Show the equipped artifacts and inventory of each of your registered accounts",
+		},
+		{
+			Name:        "missions",
+			Description: "Show the ships each of your registered accounts has in flight and when they land",
+		},
+		{
+			Name:        "missionalerts",
+			Description: "Get pinged when your ships land",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "where",
+					Description: "Where to ping you",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Direct message", Value: missionAlertDM},
+						{Name: "This channel", Value: missionAlertHere},
+						{Name: "Off", Value: missionAlertOff},
+					},
+				},
+			},
+		},
+		{
+			Name:        "craft",
+			Description: "Work out how many golden eggs crafting an artifact will cost you",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "artifact",
+					Description: "What to craft, with its tier, e.g. T4 Book of Basan",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "count",
+					Description: "How many to craft. Defaults to 1",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "ships",
+			Description: "Show the level, capacity and mission lengths of someone's ships",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Whose ships to show. Defaults to yours",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "numbers",
+			Description: "Choose how the bot writes big numbers for you",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "notation",
+					Description: "Suffixes like the game, scientific notation or just the order of magnitude",
+					Required:    false,
+					Choices:     notationChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "precision",
+					Description: "How many decimal places to show",
+					Required:    false,
+					Choices:     precisionChoices(),
+				},
+			},
 		},
 	}
 
-	commandHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, ctx context.Context){
-		"register": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, ctx context.Context) {
+	commandHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context){
+		"register": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
 			eggID := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
 
-			backup, err := api.GetBackupFromAPI(eggID)
+			// fetching the backup and syncing the rank role can take longer than Discord waits for an answer
+			if err := deferResponse(s, i, true); err != nil {
+				return
+			}
+
+			backup, err := client.GetBackup(ctx, eggID)
 			if err != nil {
-				sendErrToDiscord(s, i, err)
+				sendErrFollowup(s, i, err)
 				return
 			}
 			if backup.EiUserId != eggID {
-				sendErrToDiscord(s, i, errors.New(fmt.Sprintf(":exclamation: '%s' isn't a recognized user ID :exclamation:", eggID)))
+				sendErrFollowup(s, i, errors.New(fmt.Sprintf(":exclamation: '%s' isn't a recognized user ID :exclamation:", eggID)))
 				return
 			}
 
-			if _, err = api.AddUserToDatabase(ctx, store, backup, i.Member.User.Username); err != nil {
+			user := interactionUser(i)
+			if _, err = api.AddUserToDatabase(ctx, store, backup, i.GuildID, user.ID, user.Username); err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			if cfg.RankRoles && i.Member != nil {
+				plan, err := syncRankRoles(ctx, s, store, i.GuildID, []*discordgo.Member{i.Member}, false)
+				if err == nil && plan.Failed > 0 {
+					err = errors.New(fmt.Sprintf("%d role changes failed", plan.Failed))
+				}
+				if err != nil {
+					logrus.WithError(err).WithField("guild_id", i.GuildID).Warn("--> failed to sync rank role of new registration")
+				}
+			}
+
+			sendFollowup(s, i, &discordgo.WebhookParams{
+				Flags:   1 << 6,
+				Content: fmt.Sprintf(":tada: Congratulations! You've successfully registered %s with the bot :tada:", eggID),
+			})
+		},
+		"removeid": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			eggID := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+
+			user := interactionUser(i)
+			if err := api.RemoveUserFromDatabase(ctx, store, i.GuildID, eggID, user.ID, user.Username); err != nil {
 				sendErrToDiscord(s, i, err)
 				return
 			}
@@ -68,70 +296,494 @@ var (
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
 					Flags:   1 << 6,
-					Content: fmt.Sprintf(":tada: Congratulations! You've successfully registered %s with the bot :tada:", eggID),
+					Content: fmt.Sprint(":frowning2: Sad to see you go, but your account has been successfully removed from the bot :frowning2:"),
 				},
 			}); err != nil {
 				sendErrToDiscord(s, i, err)
 			}
 		},
-		"removeid": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, ctx context.Context) {
-			eggID := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+		"board": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			// refreshing the shared message and drawing the caller's page can outlast the 3 seconds Discord waits
+			if err := deferResponse(s, i, true); err != nil {
+				return
+			}
+
+			tx, err := store.Transaction(ctx)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+			leaderboard, err := tx.GetLeaderboardByGuildID(i.GuildID)
+			_ = tx.Commit()
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			// the caller gets their page either way, and the shared message is only refreshed when there is one
+			content := "There's no leaderboard channel for this server yet, so here's a copy just for you. An admin can pick one with /setboard"
+			if err == nil {
+				if leaderboard, err = postLeaderboard(ctx, s, store, leaderboard, cfg.RefreshInterval()); err != nil {
+					sendErrFollowup(s, i, err)
+					return
+				}
+				content = fmt.Sprintf(":trophy: The leaderboard in <#%s> is up to date :trophy:", leaderboard.ChannelID)
+			}
+
+			metric := api.DefaultMetric
+			if option, ok := optionMap(i)["metric"]; ok {
+				metric = option.StringValue()
+			}
+
+			// reply with the page the caller is on
+			data, err := privateLeaderboard(ctx, s, i, store, cfg, metric, 0, true)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			sendFollowup(s, i, &discordgo.WebhookParams{
+				Flags:      data.Flags,
+				Content:    content,
+				Embeds:     data.Embeds,
+				Components: data.Components,
+			})
+		},
+		"setboard": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			if !isAdmin(i) {
+				sendErrToDiscord(s, i, errors.New(":no_entry: Only server admins can move the leaderboard :no_entry:"))
+				return
+			}
+
+			// moving the leaderboard deletes the old message and posts a new one, which can outlast the 3 seconds Discord waits
+			if err := deferResponse(s, i, true); err != nil {
+				return
+			}
+
+			channelID := optionMap(i)["channel"].ChannelValue(nil).ID
+			leaderboard, err := moveLeaderboard(ctx, s, store, i.GuildID, channelID, cfg.RefreshInterval())
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			sendFollowup(s, i, &discordgo.WebhookParams{
+				Flags:   1 << 6,
+				Content: fmt.Sprintf(":trophy: The leaderboard now lives in <#%s> and will update itself every refresh :trophy:", leaderboard.ChannelID),
+			})
+		},
+		"gainers": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			embed, err := api.BuildGainersLeaderboard(ctx, store, i.GuildID, optionMap(i)["period"].StringValue(), displayNames(s, i.GuildID), numberFormat(ctx, store, interactionUser(i).ID))
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+
+			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{embed},
+				},
+			}); err != nil {
+				sendErrToDiscord(s, i, err)
+			}
+		},
+		"coop": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			options := optionMap(i)
+			contractID := strings.TrimSpace(options["contract"].StringValue())
+			code := strings.TrimSpace(options["code"].StringValue())
+
+			status, err := client.GetCoopStatus(ctx, contractID, code)
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+
+			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{api.BuildCoopStatusEmbed(status, numberFormat(ctx, store, interactionUser(i).ID))},
+				},
+			}); err != nil {
+				sendErrToDiscord(s, i, err)
+			}
+		},
+		"contracts": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			periodicals, err := client.GetPeriodicals(ctx)
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+
+			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{api.BuildContractsEmbed(periodicals, numberFormat(ctx, store, interactionUser(i).ID))},
+				},
+			}); err != nil {
+				sendErrToDiscord(s, i, err)
+			}
+		},
+		"rank": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			user := interactionUser(i)
+			users, err := api.GetDiscordUsers(ctx, store, user.ID, user.Username)
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+
+			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{api.BuildRankEmbed(users, numberFormat(ctx, store, user.ID))},
+				},
+			}); err != nil {
+				sendErrToDiscord(s, i, err)
+			}
+		},
+		"eb": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			options := optionMap(i)
 
-			if err := api.RemoveUserFromDatabase(ctx, store, eggID, i.Member.User.Username); err != nil {
+			// research the caller didn't give falls back to their best registered account, or maxed out
+			soulFood, prophecyBonus := int64(api.MaxSoulFood), int64(api.MaxProphecyBonus)
+			caller := interactionUser(i)
+			if users, err := api.GetDiscordUsers(ctx, store, caller.ID, caller.Username); err == nil {
+				best := users[0]
+				for _, user := range users[1:] {
+					if api.GetRank(user).EB > api.GetRank(best).EB {
+						best = user
+					}
+				}
+				soulFood, prophecyBonus = int64(best.SoulFood), int64(best.ProphecyBonus)
+			}
+			if option, ok := options["soul_food"]; ok {
+				soulFood = option.IntValue()
+			}
+			if option, ok := options["prophecy_bonus"]; ok {
+				prophecyBonus = option.IntValue()
+			}
+
+			user, err := api.HypotheticalUser(options["se"].StringValue(), options["pe"].IntValue(), soulFood, prophecyBonus)
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+
+			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{api.BuildEBEmbed(user, numberFormat(ctx, store, caller.ID))},
+				},
+			}); err != nil {
+				sendErrToDiscord(s, i, err)
+			}
+		},
+		"profile": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			user := chosenUser(i)
+			users, err := registeredUsers(ctx, store, i, user)
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+
+			name := displayNames(s, i.GuildID)(users[0])
+			if name == "" {
+				name = user.Username
+			}
+
+			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{api.BuildProfileEmbed(name, users, numberFormat(ctx, store, interactionUser(i).ID))},
+				},
+			}); err != nil {
+				sendErrToDiscord(s, i, err)
+			}
+		},
+		"syncroles": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			if !isAdmin(i) {
+				sendErrToDiscord(s, i, errors.New(":no_entry: Only server admins can sync rank roles :no_entry:"))
+				return
+			}
+
+			dryRun := true
+			if option, ok := optionMap(i)["dryrun"]; ok {
+				dryRun = option.BoolValue()
+			}
+
+			// listing members and changing roles one at a time easily outlasts Discord's deadline for an answer
+			if err := deferResponse(s, i, true); err != nil {
+				return
+			}
+
+			plan, err := syncGuildRankRoles(ctx, s, store, i.GuildID, dryRun)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			content := plan.String()
+			switch {
+			case dryRun:
+			case plan.Failed > 0:
+				content = fmt.Sprintf(":warning: Synced rank roles for %d members, but %d role changes failed. Check that the bot's role is above the rank roles and that it can manage roles :warning:",
+					len(plan.Changes), plan.Failed)
+			default:
+				content = fmt.Sprintf(":white_check_mark: Synced rank roles for %d members :white_check_mark:", len(plan.Changes))
+			}
+
+			sendFollowup(s, i, &discordgo.WebhookParams{
+				Flags:   1 << 6,
+				Content: content,
+				// the dry run names members without pinging them
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			})
+		},
+		"artifacts": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			users, err := registeredUsers(ctx, store, i, interactionUser(i))
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+			if err = deferResponse(s, i, false); err != nil {
+				return
+			}
+
+			backups, err := fetchBackups(ctx, client, users)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			numbers := numberFormat(ctx, store, interactionUser(i).ID)
+			embeds := make([]*discordgo.MessageEmbed, 0, len(backups))
+			for _, backup := range backups {
+				embeds = append(embeds, api.BuildArtifactsEmbed(backup, numbers))
+			}
+
+			sendEmbedFollowups(s, i, embeds)
+		},
+		"missions": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			users, err := registeredUsers(ctx, store, i, interactionUser(i))
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+			if err = deferResponse(s, i, false); err != nil {
+				return
+			}
+
+			backups, err := fetchBackups(ctx, client, users)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			now := time.Now()
+			embeds := make([]*discordgo.MessageEmbed, 0, len(backups))
+			for _, backup := range backups {
+				// the fresh backup may have launched ships since the last refresh
+				if err = api.SaveMissions(ctx, store, backup); err != nil {
+					logrus.WithError(err).Warn("--> failed to save missions")
+				}
+				embeds = append(embeds, api.BuildMissionsEmbed(backup, now))
+			}
+
+			sendEmbedFollowups(s, i, embeds)
+		},
+		"missionalerts": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			where := optionMap(i)["where"].StringValue()
+			if where == missionAlertHere && i.GuildID == "" {
+				// a DM with the bot is already a direct message
+				where = missionAlertDM
+			}
+
+			if err := setMissionAlert(ctx, store, interactionUser(i).ID, where, i.ChannelID); err != nil {
 				sendErrToDiscord(s, i, err)
 				return
 			}
 
+			content := ":rocket: You'll get a direct message whenever one of your ships lands :rocket:"
+			switch where {
+			case missionAlertHere:
+				content = fmt.Sprintf(":rocket: You'll be pinged in <#%s> whenever one of your ships lands :rocket:", i.ChannelID)
+			case missionAlertOff:
+				content = "You won't hear about your ships landing any more"
+			}
+
 			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
 					Flags:   1 << 6,
-					Content: fmt.Sprint(":frowning2: Sad to see you go, but your account has been successfully removed from the bot :frowning2:"),
+					Content: content,
 				},
 			}); err != nil {
 				sendErrToDiscord(s, i, err)
 			}
 		},
-		"board": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, ctx context.Context) {
-			embed, err := api.BuildSELeaderboard(ctx, store)
+		"craft": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			options := optionMap(i)
+			spec, err := api.ParseArtifactName(options["artifact"].StringValue())
 			if err != nil {
 				sendErrToDiscord(s, i, err)
+				return
+			}
+			count := 1
+			if option, ok := options["count"]; ok {
+				count = int(option.IntValue())
 			}
 
-			messages, err := s.ChannelMessages(i.ChannelID, 100, "", "", "")
+			users, err := registeredUsers(ctx, store, i, interactionUser(i))
 			if err != nil {
 				sendErrToDiscord(s, i, err)
+				return
+			}
+			if err = deferResponse(s, i, false); err != nil {
+				return
 			}
 
-			mIDs := make([]string, 0)
-			for _, message := range messages {
-				if !message.Pinned {
-					mIDs = append(mIDs, message.ID)
+			artifactsConfig, err := client.ArtifactsConfig(ctx)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+			backups, err := fetchBackups(ctx, client, users)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			numbers := numberFormat(ctx, store, interactionUser(i).ID)
+			embeds := make([]*discordgo.MessageEmbed, 0, len(backups))
+			for _, backup := range backups {
+				quote, err := api.QuoteCraft(artifactsConfig, backup, spec, count)
+				if err != nil {
+					sendErrFollowup(s, i, err)
+					return
 				}
+				embeds = append(embeds, api.BuildCraftEmbed(backup.GetUserName(), quote, numbers))
 			}
 
-			if err = s.ChannelMessagesBulkDelete(i.ChannelID, mIDs); err != nil {
+			sendEmbedFollowups(s, i, embeds)
+		},
+		"ships": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			users, err := registeredUsers(ctx, store, i, chosenUser(i))
+			if err != nil {
 				sendErrToDiscord(s, i, err)
+				return
+			}
+			if err = deferResponse(s, i, false); err != nil {
+				return
+			}
+
+			artifactsConfig, err := client.ArtifactsConfig(ctx)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+			backups, err := fetchBackups(ctx, client, users)
+			if err != nil {
+				sendErrFollowup(s, i, err)
+				return
+			}
+
+			embeds := make([]*discordgo.MessageEmbed, 0, len(backups))
+			for _, backup := range backups {
+				embeds = append(embeds, api.BuildShipsEmbed(backup, artifactsConfig))
+			}
+
+			sendEmbedFollowups(s, i, embeds)
+		},
+		"numbers": func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			options := optionMap(i)
+
+			var notation *format.Notation
+			if option, ok := options["notation"]; ok {
+				chosen := format.Notation(option.StringValue())
+				notation = &chosen
+			}
+			var precision *int
+			if option, ok := options["precision"]; ok {
+				chosen := int(option.IntValue())
+				precision = &chosen
+			}
+
+			numbers, err := setNumberFormat(ctx, store, interactionUser(i).ID, notation, precision)
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
 			}
 
 			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Embeds: []*discordgo.MessageEmbed{embed},
+					Flags:   1 << 6,
+					Content: fmt.Sprintf(":abacus: Got it! Numbers will look like %s from now on :abacus:", numbers.Number(1.5e18)),
 				},
 			}); err != nil {
 				sendErrToDiscord(s, i, err)
 			}
 		},
 	}
+
+	// componentHandlers handle clicks on message components, keyed by the part of their custom ID before the first colon
+	componentHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context){
+		leaderboardComponent: func(s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, client *api.Client, cfg config.Bot, ctx context.Context) {
+			action, metric, page, err := parseLeaderboardButton(i.MessageComponentData().CustomID)
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+
+			data, err := privateLeaderboard(ctx, s, i, store, cfg, metric, page, false)
+			if err != nil {
+				sendErrToDiscord(s, i, err)
+				return
+			}
+
+			// the shared channel message opens a private copy, which then pages itself
+			responseType := discordgo.InteractionResponseUpdateMessage
+			if action == leaderboardOpen {
+				responseType = discordgo.InteractionResponseChannelMessageWithSource
+			}
+
+			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: responseType,
+				Data: data,
+			}); err != nil {
+				sendErrToDiscord(s, i, err)
+			}
+		},
+	}
 )
 
-// Start initializes the Discord bot by adding handlers and registering commands
-func Start(ctx context.Context, store datastore.Database) ([]*discordgo.ApplicationCommand, *discordgo.Session) {
-	s, err := discordgo.New(fmt.Sprintf("Bot %s", config.Config.Token))
+// Bot is a running Discord bot along with the commands it has registered
+type Bot struct {
+	Session *discordgo.Session
+
+	store  datastore.Database
+	client *api.Client
+
+	mu sync.Mutex
+	// config is the running configuration, which Reload swaps out
+	config config.Bot
+	// commands holds the registered commands keyed by guild ID, with global commands under ""
+	commands map[string][]*discordgo.ApplicationCommand
+}
+
+// Start initializes the Discord bot by adding handlers and registering commands, either globally
+// or in every guild the bot is in as it joins them
+func Start(ctx context.Context, cfg config.Bot, store datastore.Database, client *api.Client) *Bot {
+	s, err := discordgo.New(fmt.Sprintf("Bot %s", cfg.Token))
 	if err != nil {
 		panic(err)
 	}
+	// member events keep nicknames in the state current, so leaderboards rarely need to ask Discord for them. Without
+	// them nicknames are fetched when needed, and the privileged intent stays opt-in since connecting fails without it.
+	s.Identify.Intents = discordgo.IntentsAllWithoutPrivileged
+	if cfg.MemberIntent {
+		s.Identify.Intents |= discordgo.IntentsGuildMembers
+	}
 
 	u, err := s.User("@me")
 	if err != nil {
@@ -140,29 +792,124 @@ func Start(ctx context.Context, store datastore.Database) ([]*discordgo.Applicat
 
 	logrus.Infof("--> logged in as %v#%v", u.Username, u.Discriminator)
 
+	b := &Bot{
+		Session:  s,
+		store:    store,
+		client:   client,
+		config:   cfg,
+		commands: make(map[string][]*discordgo.ApplicationCommand),
+	}
+
 	s.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			h(s, i, store, ctx)
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
+				h(s, i, store, client, b.Config(), ctx)
+			}
+		case discordgo.InteractionMessageComponent:
+			prefix := strings.SplitN(i.MessageComponentData().CustomID, ":", 2)[0]
+			if h, ok := componentHandlers[prefix]; ok {
+				h(s, i, store, client, b.Config(), ctx)
+			}
 		}
 	})
 
+	if !cfg.GlobalCommands {
+		// fired for every guild once connected, and again whenever the bot is added to a new one
+		s.AddHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
+			if registerErr := b.registerCommands(g.ID); registerErr != nil {
+				logrus.WithError(registerErr).WithField("guild_id", g.ID).Error("--> failed to register commands")
+			}
+		})
+	}
+
 	if err = s.Open(); err != nil {
 		panic(err)
 	}
 
-	registeredCommands := make([]*discordgo.ApplicationCommand, len(commands))
-	for i, command := range commands {
-		cmd, cmdCreateErr := s.ApplicationCommandCreate(s.State.User.ID, config.Config.GuildID, command)
-		if cmdCreateErr != nil {
-			panic(cmdCreateErr)
+	if cfg.GuildID != "" {
+		if err = backfillGuildUsers(ctx, store, cfg.GuildID); err != nil {
+			logrus.WithError(err).Warn("--> failed to assign existing users to a guild")
+		}
+		if err = backfillDiscordIDs(ctx, s, store, cfg.GuildID); err != nil {
+			logrus.WithError(err).Warn("--> failed to backfill Discord IDs of existing users")
 		}
+	}
+
+	b.pinLeaderboardChannels(ctx, cfg)
 
-		registeredCommands[i] = cmd
+	if cfg.GlobalCommands {
+		if err = b.registerCommands(""); err != nil {
+			panic(err)
+		}
 	}
 
 	logrus.Info("--> bot is running")
 
-	return registeredCommands, s
+	return b
+}
+
+// Config is the configuration the bot is currently running with
+func (b *Bot) Config() config.Bot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config
+}
+
+// Reload switches the bot over to settings that can change while it runs, moving any leaderboards
+// whose configured channel has changed. Settings that need a restart are kept as they were.
+func (b *Bot) Reload(ctx context.Context, next config.Bot) {
+	b.mu.Lock()
+	cfg, needRestart := b.config.Reload(next)
+	b.config = cfg
+	b.mu.Unlock()
+
+	if len(needRestart) > 0 {
+		logrus.Warnf("--> restart the bot to apply changes to: %s", strings.Join(needRestart, ", "))
+	}
+
+	b.pinLeaderboardChannels(ctx, cfg)
+	logrus.Info("--> reloaded config")
+}
+
+// registerCommands registers every command in a guild, or globally when guildID is empty
+func (b *Bot) registerCommands(guildID string) error {
+	registered, err := b.Session.ApplicationCommandBulkOverwrite(b.Session.State.User.ID, guildID, commands)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.commands[guildID] = registered
+
+	return nil
+}
+
+// RemoveCommands removes every command the bot registered, globally and from each guild
+func (b *Bot) RemoveCommands() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for guildID, registered := range b.commands {
+		for _, command := range registered {
+			if err := b.Session.ApplicationCommandDelete(b.Session.State.User.ID, guildID, command.ID); err != nil {
+				return err
+			}
+		}
+		delete(b.commands, guildID)
+	}
+
+	return nil
+}
+
+// metricChoices offers every leaderboard metric in the /board command
+func metricChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(api.MetricNames))
+	for _, name := range api.MetricNames {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: api.Metrics[name].Choice, Value: name})
+	}
+	return choices
 }
 
 func sendErrToDiscord(s *discordgo.Session, i *discordgo.InteractionCreate, input error) {
@@ -176,3 +923,55 @@ func sendErrToDiscord(s *discordgo.Session, i *discordgo.InteractionCreate, inpu
 		logrus.Fatal(err)
 	}
 }
+
+// deferResponse acknowledges an interaction straight away, for commands that can take longer than the 3 seconds
+// Discord waits for an answer. The answer then goes out with sendFollowup, visible only to the caller when ephemeral.
+func deferResponse(s *discordgo.Session, i *discordgo.InteractionCreate, ephemeral bool) error {
+	var flags uint64
+	if ephemeral {
+		flags = 1 << 6
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: flags,
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("--> failed to defer the response to an interaction")
+	}
+	return err
+}
+
+// sendFollowup answers an interaction that deferResponse acknowledged
+func sendFollowup(s *discordgo.Session, i *discordgo.InteractionCreate, params *discordgo.WebhookParams) {
+	if _, err := s.FollowupMessageCreate(s.State.User.ID, i.Interaction, true, params); err != nil {
+		logrus.WithError(err).Warn("--> failed to send a followup message")
+	}
+}
+
+// sendErrFollowup tells the caller what went wrong with an interaction that deferResponse acknowledged
+func sendErrFollowup(s *discordgo.Session, i *discordgo.InteractionCreate, input error) {
+	sendFollowup(s, i, &discordgo.WebhookParams{
+		Flags:   1 << 6,
+		Content: input.Error(),
+	})
+}
+
+// optionMap indexes a command's options by name, since Discord doesn't guarantee they arrive in declaration order
+func optionMap(i *discordgo.InteractionCreate) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range i.ApplicationCommandData().Options {
+		options[option.Name] = option
+	}
+	return options
+}
+
+// isAdmin reports whether the member running a command may manage the server
+func isAdmin(i *discordgo.InteractionCreate) bool {
+	if i.Member == nil {
+		return false
+	}
+	return i.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) != 0
+}
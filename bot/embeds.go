@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxMessageEmbedLength is the most text Discord allows across all the embeds of a single message
+const maxMessageEmbedLength = 6000
+
+// embedLength counts the text of an embed the way Discord does against maxMessageEmbedLength
+func embedLength(embed *discordgo.MessageEmbed) int {
+	length := len(embed.Title) + len(embed.Description)
+	for _, field := range embed.Fields {
+		length += len(field.Name) + len(field.Value)
+	}
+	if embed.Footer != nil {
+		length += len(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		length += len(embed.Author.Name)
+	}
+	return length
+}
+
+// embedMessages splits embeds into as few messages as Discord will accept, keeping their order. An embed too long
+// for a message of its own still gets one, for Discord to turn down on its own.
+func embedMessages(embeds []*discordgo.MessageEmbed) [][]*discordgo.MessageEmbed {
+	var messages [][]*discordgo.MessageEmbed
+	var current []*discordgo.MessageEmbed
+	length := 0
+	for _, embed := range embeds {
+		n := embedLength(embed)
+		if len(current) > 0 && (len(current) == maxMessageEmbeds || length+n > maxMessageEmbedLength) {
+			messages = append(messages, current)
+			current, length = nil, 0
+		}
+		current = append(current, embed)
+		length += n
+	}
+	if len(current) > 0 {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// sendEmbedFollowups answers an interaction that deferResponse acknowledged with embeds, spread over as many messages
+// as it takes to stay within Discord's limits
+func sendEmbedFollowups(s *discordgo.Session, i *discordgo.InteractionCreate, embeds []*discordgo.MessageEmbed) {
+	for _, message := range embedMessages(embeds) {
+		sendFollowup(s, i, &discordgo.WebhookParams{Embeds: message})
+	}
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedMessages(t *testing.T) {
+	// about 4000 characters each, like a well-stocked /artifacts embed
+	long := func(title string) *discordgo.MessageEmbed {
+		embed := &discordgo.MessageEmbed{Title: title}
+		for n := 0; n < 4; n++ {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Field", Value: strings.Repeat("x", 1000)})
+		}
+		return embed
+	}
+	short := func(title string) *discordgo.MessageEmbed {
+		return &discordgo.MessageEmbed{Title: title, Description: "short"}
+	}
+
+	tests := []struct {
+		name   string
+		embeds []*discordgo.MessageEmbed
+		sizes  []int
+	}{
+		{name: "none", embeds: nil, sizes: nil},
+		{name: "short embeds share a message", embeds: []*discordgo.MessageEmbed{short("a"), short("b"), short("c")}, sizes: []int{3}},
+		{name: "long embeds each get a message", embeds: []*discordgo.MessageEmbed{long("a"), long("b")}, sizes: []int{1, 1}},
+		{name: "short embeds fill in after a long one", embeds: []*discordgo.MessageEmbed{long("a"), short("b"), long("c")}, sizes: []int{2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := embedMessages(tt.embeds)
+			var sizes []int
+			for _, message := range messages {
+				sizes = append(sizes, len(message))
+				total := 0
+				for _, embed := range message {
+					total += embedLength(embed)
+				}
+				require.LessOrEqual(t, total, maxMessageEmbedLength)
+			}
+			require.Equal(t, tt.sizes, sizes)
+		})
+	}
+
+	t.Run("at most ten embeds a message", func(t *testing.T) {
+		embeds := make([]*discordgo.MessageEmbed, maxMessageEmbeds+1)
+		for n := range embeds {
+			embeds[n] = short("a")
+		}
+		messages := embedMessages(embeds)
+		require.Len(t, messages, 2)
+		require.Len(t, messages[0], maxMessageEmbeds)
+	})
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"context"
+	"egg/api"
+	"egg/datastore"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// eventPollInterval is how often the game is asked for new events and sales
+	eventPollInterval = 5 * time.Minute
+	// eventRetention is how long an event or sale is remembered after it ends, so one the game still reports a little
+	// past its end isn't posted again
+	eventRetention = time.Hour
+)
+
+// newAnnouncements fetches the events and sales running now and returns those that haven't been posted yet
+func newAnnouncements(ctx context.Context, store datastore.Database, client *api.Client, now time.Time) ([]api.Announcement, error) {
+	periodicals, err := client.GetPeriodicals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	announcements := api.Announcements(periodicals, now)
+
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	identifiers := make([]string, 0, len(announcements))
+	for _, announcement := range announcements {
+		identifiers = append(identifiers, announcement.Identifier)
+	}
+	announced, err := tx.GetAnnouncedEvents(identifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	posted := make(map[string]bool, len(announced))
+	for _, event := range announced {
+		posted[event.Identifier] = true
+	}
+	var fresh []api.Announcement
+	for _, announcement := range announcements {
+		if !posted[announcement.Identifier] {
+			fresh = append(fresh, announcement)
+		}
+	}
+
+	return fresh, nil
+}
+
+// rememberAnnouncements records that events and sales have been posted and forgets those that ended a while ago
+func rememberAnnouncements(ctx context.Context, store datastore.Database, announcements []api.Announcement, now time.Time) error {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	events := make(datastore.AnnouncedEvents, 0, len(announcements))
+	for _, announcement := range announcements {
+		events = append(events, datastore.AnnouncedEvent{Identifier: announcement.Identifier, EndsAt: announcement.EndsAt})
+	}
+	if err = tx.SaveAnnouncedEvents(events); err != nil {
+		return err
+	}
+	err = tx.DeleteAnnouncedEventsBefore(now.Add(-eventRetention))
+	return err
+}
+
+// announceEvents posts events and sales that started since the last check to every configured channel, remembering
+// each one whether or not every post could be sent so nothing is posted twice
+func announceEvents(ctx context.Context, s *discordgo.Session, store datastore.Database, client *api.Client, channels map[string]string, now time.Time) error {
+	announcements, err := newAnnouncements(ctx, store, client, now)
+	if err != nil {
+		return err
+	}
+
+	if len(announcements) > 0 {
+		embed := api.BuildAnnouncementsEmbed(announcements)
+		for guildID, channelID := range channels {
+			if _, sendErr := s.ChannelMessageSendEmbed(channelID, embed); sendErr != nil {
+				logrus.WithError(sendErr).WithField("guild_id", guildID).Warn("--> failed to announce events")
+			}
+		}
+	}
+
+	return rememberAnnouncements(ctx, store, announcements, now)
+}
+
+// RunEventAnnouncements polls the game for new events and sales until ctx is cancelled, posting them to the
+// configured event channels. Nothing is fetched while no channels are configured.
+func (b *Bot) RunEventAnnouncements(ctx context.Context) {
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			channels := b.Config().EventChannels
+			if len(channels) == 0 {
+				continue
+			}
+			if err := announceEvents(ctx, b.Session, b.store, b.client, channels, now); err != nil {
+				logrus.WithError(err).Warn("--> failed to announce events")
+			}
+		}
+	}
+}
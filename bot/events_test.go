@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"context"
+	"egg/api"
+	"egg/api/apitest"
+	"egg/datastore"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAnnouncements(t *testing.T) {
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+	store := datastore.Database{DB: db}
+	ctx := context.Background()
+
+	server := apitest.NewServer()
+	defer server.Close()
+	periodicals := apitest.Periodicals()
+	server.SetPeriodicals(periodicals)
+	client := server.APIClient()
+
+	now := time.Now()
+	announcements, err := newAnnouncements(ctx, store, client, now)
+	require.NoError(t, err)
+	require.Len(t, announcements, 3)
+	require.NoError(t, rememberAnnouncements(ctx, store, announcements, now))
+
+	// the next poll, or the first after a restart, finds nothing new
+	announcements, err = newAnnouncements(ctx, store, client, now.Add(eventPollInterval))
+	require.NoError(t, err)
+	require.Empty(t, announcements)
+
+	periodicals.Events.Events = append(periodicals.Events.Events, &api.Event{Id: "f00d", EventType: "gift-boost", Multiplier: 4, SecondsRemaining: 3600})
+	server.SetPeriodicals(periodicals)
+	announcements, err = newAnnouncements(ctx, store, client, now.Add(eventPollInterval))
+	require.NoError(t, err)
+	require.Len(t, announcements, 1)
+	require.Equal(t, "event:f00d", announcements[0].Identifier)
+	require.NoError(t, rememberAnnouncements(ctx, store, announcements, now.Add(eventPollInterval)))
+
+	// the piggy sale is forgotten once it has been over for a while, so its next run is posted
+	later := now.Add(time.Hour + eventRetention + time.Minute)
+	require.NoError(t, rememberAnnouncements(ctx, store, nil, later))
+	announcements, err = newAnnouncements(ctx, store, client, later)
+	require.NoError(t, err)
+	require.Len(t, announcements, 1)
+	require.Equal(t, "sale:gametime-piggy-sale", announcements[0].Identifier)
+
+	t.Run("fetching fails", func(t *testing.T) {
+		server.SetFault(apitest.PathPeriodicals, apitest.Fault{StatusCode: 500})
+		defer server.ClearFaults()
+
+		_, err := newAnnouncements(ctx, store, client, now)
+		require.Error(t, err)
+	})
+}
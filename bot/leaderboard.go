@@ -0,0 +1,217 @@
+package bot
+
+import (
+	"context"
+	"egg/api"
+	"egg/config"
+	"egg/datastore"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// UpdateLeaderboards edits every guild's leaderboard message in place, reposting any that have gone missing
+func UpdateLeaderboards(ctx context.Context, s *discordgo.Session, store datastore.Database, refreshInterval time.Duration) error {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+	leaderboards, err := tx.GetLeaderboards()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, leaderboard := range leaderboards {
+		if _, postErr := postLeaderboard(ctx, s, store, leaderboard, refreshInterval); postErr != nil {
+			logrus.WithError(postErr).WithField("guild_id", leaderboard.GuildID).Warn("--> failed to update leaderboard")
+		}
+	}
+
+	return nil
+}
+
+// postLeaderboard edits a guild's leaderboard message, or sends a new one if there isn't one yet, and records where it lives
+func postLeaderboard(ctx context.Context, s *discordgo.Session, store datastore.Database, leaderboard datastore.Leaderboard, refreshInterval time.Duration) (datastore.Leaderboard, error) {
+	board, err := api.BuildLeaderboard(ctx, store, api.LeaderboardQuery{
+		GuildID:         leaderboard.GuildID,
+		RefreshInterval: refreshInterval,
+		Names:           displayNames(s, leaderboard.GuildID),
+	})
+	if err != nil {
+		return leaderboard, err
+	}
+	// the channel's message is shared, so its buttons open a private copy rather than paging it for everyone
+	components := leaderboardButtons(leaderboardOpen, board)
+
+	if leaderboard.MessageID != "" {
+		_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:         leaderboard.MessageID,
+			Channel:    leaderboard.ChannelID,
+			Embeds:     []*discordgo.MessageEmbed{board.Embed},
+			Components: components,
+		})
+		switch {
+		case err == nil:
+			return leaderboard, nil
+		case !isNotFound(err):
+			return leaderboard, err
+		}
+		// somebody deleted the message, so fall through and post a fresh one
+	}
+
+	message, err := s.ChannelMessageSendComplex(leaderboard.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{board.Embed},
+		Components: components,
+	})
+	if err != nil {
+		return leaderboard, err
+	}
+	leaderboard.MessageID = message.ID
+
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return leaderboard, err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	leaderboard, err = tx.CreateOrUpdateLeaderboard(leaderboard)
+	return leaderboard, err
+}
+
+// moveLeaderboard points a guild's leaderboard at a new channel, removing the message from the old one
+func moveLeaderboard(ctx context.Context, s *discordgo.Session, store datastore.Database, guildID, channelID string, refreshInterval time.Duration) (datastore.Leaderboard, error) {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return datastore.Leaderboard{}, err
+	}
+	previous, lookupErr := tx.GetLeaderboardByGuildID(guildID)
+	if err = tx.Commit(); err != nil {
+		return datastore.Leaderboard{}, err
+	}
+
+	if lookupErr == nil && previous.MessageID != "" {
+		if deleteErr := s.ChannelMessageDelete(previous.ChannelID, previous.MessageID); deleteErr != nil && !isNotFound(deleteErr) {
+			logrus.WithError(deleteErr).WithField("guild_id", guildID).Warn("--> failed to remove old leaderboard message")
+		}
+	}
+
+	return postLeaderboard(ctx, s, store, datastore.Leaderboard{GuildID: guildID, ChannelID: channelID}, refreshInterval)
+}
+
+// Leaderboard buttons carry a custom ID of "leaderboard:<action>:<metric>:<page>"
+const (
+	leaderboardComponent = "leaderboard"
+	// leaderboardOpen replies with a private copy of the requested page
+	leaderboardOpen = "open"
+	// leaderboardPage pages a private copy in place
+	leaderboardPage = "page"
+)
+
+// leaderboardButtons are the previous and next page buttons shown under a leaderboard page
+func leaderboardButtons(action string, board api.LeaderboardPage) []discordgo.MessageComponent {
+	if board.Pages <= 1 {
+		return []discordgo.MessageComponent{}
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					Disabled: board.Page == 0,
+					CustomID: fmt.Sprintf("%s:%s:%s:%d", leaderboardComponent, action, board.Metric, board.Page-1),
+				},
+				discordgo.Button{
+					Label:    "Next",
+					Style:    discordgo.SecondaryButton,
+					Disabled: board.Page >= board.Pages-1,
+					CustomID: fmt.Sprintf("%s:%s:%s:%d", leaderboardComponent, action, board.Metric, board.Page+1),
+				},
+			},
+		},
+	}
+}
+
+// privateLeaderboard builds a page of a guild's leaderboard for whoever is interacting with it, calling out their accounts
+func privateLeaderboard(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, store datastore.Database, cfg config.Bot, metric string, page int, highlightPage bool) (*discordgo.InteractionResponseData, error) {
+	board, err := api.BuildLeaderboard(ctx, store, api.LeaderboardQuery{
+		GuildID:         i.GuildID,
+		Metric:          metric,
+		Page:            page,
+		Highlight:       interactionUser(i).ID,
+		HighlightPage:   highlightPage,
+		Numbers:         numberFormat(ctx, store, interactionUser(i).ID),
+		RefreshInterval: cfg.RefreshInterval(),
+		Names:           displayNames(s, i.GuildID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &discordgo.InteractionResponseData{
+		Flags:      1 << 6,
+		Embeds:     []*discordgo.MessageEmbed{board.Embed},
+		Components: leaderboardButtons(leaderboardPage, board),
+	}, nil
+}
+
+// parseLeaderboardButton splits a leaderboard button's custom ID into its action, metric and page
+func parseLeaderboardButton(customID string) (string, string, int, error) {
+	parts := strings.Split(customID, ":")
+	if len(parts) != 4 || parts[0] != leaderboardComponent {
+		return "", "", 0, errors.New(fmt.Sprintf("'%s' isn't a leaderboard button", customID))
+	}
+	page, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return "", "", 0, errors.New(fmt.Sprintf("'%s' isn't a leaderboard button", customID))
+	}
+	return parts[1], parts[2], page, nil
+}
+
+// pinLeaderboardChannels moves the leaderboard of every guild in cfg.LeaderboardChannels that isn't in its configured channel
+func (b *Bot) pinLeaderboardChannels(ctx context.Context, cfg config.Bot) {
+	for guildID, channelID := range cfg.LeaderboardChannels {
+		tx, err := b.store.Transaction(ctx)
+		if err != nil {
+			logrus.WithError(err).Warn("--> failed to look up leaderboards")
+			return
+		}
+		current, lookupErr := tx.GetLeaderboardByGuildID(guildID)
+		if err = tx.Commit(); err != nil {
+			logrus.WithError(err).Warn("--> failed to look up leaderboards")
+			return
+		}
+		if lookupErr == nil && current.ChannelID == channelID {
+			continue
+		}
+
+		if _, err = moveLeaderboard(ctx, b.Session, b.store, guildID, channelID, cfg.RefreshInterval()); err != nil {
+			logrus.WithError(err).WithField("guild_id", guildID).Warn("--> failed to move leaderboard to its configured channel")
+		}
+	}
+}
+
+func isNotFound(err error) bool {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		return restErr.Response.StatusCode == http.StatusNotFound
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"egg/api"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderboardButtons(t *testing.T) {
+	require.Empty(t, leaderboardButtons(leaderboardOpen, api.LeaderboardPage{Pages: 1}))
+
+	buttons := leaderboardButtons(leaderboardPage, api.LeaderboardPage{Metric: "pe", Page: 0, Pages: 3})
+	row := buttons[0].(discordgo.ActionsRow)
+	previous, next := row.Components[0].(discordgo.Button), row.Components[1].(discordgo.Button)
+	require.True(t, previous.Disabled)
+	require.False(t, next.Disabled)
+	require.Equal(t, "leaderboard:page:pe:1", next.CustomID)
+
+	action, metric, page, err := parseLeaderboardButton(next.CustomID)
+	require.NoError(t, err)
+	require.Equal(t, leaderboardPage, action)
+	require.Equal(t, "pe", metric)
+	require.Equal(t, 1, page)
+
+	buttons = leaderboardButtons(leaderboardOpen, api.LeaderboardPage{Metric: "pe", Page: 2, Pages: 3})
+	row = buttons[0].(discordgo.ActionsRow)
+	require.False(t, row.Components[0].(discordgo.Button).Disabled)
+	require.True(t, row.Components[1].(discordgo.Button).Disabled)
+
+	for _, customID := range []string{"leaderboard:page:pe", "leaderboard:page:pe:two", "other:page:pe:1"} {
+		_, _, _, err = parseLeaderboardButton(customID)
+		require.Error(t, err, customID)
+	}
+}
@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"context"
+	"egg/api"
+	"egg/datastore"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// interactionUser returns whoever ran a command, whether it came from a guild or a DM
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// displayNames resolves registered users to their current nickname or username in a guild. Members are looked up in
+// the session's state, which member events keep current, and only fetched from Discord when the state doesn't have
+// them yet, since leaderboards are drawn within the 3 seconds Discord gives a button click to be answered.
+func displayNames(s *discordgo.Session, guildID string) api.NameResolver {
+	cache := make(map[string]string)
+	return func(user datastore.User) string {
+		if user.DiscordID == "" || guildID == "" {
+			return ""
+		}
+		if name, ok := cache[user.DiscordID]; ok {
+			return name
+		}
+
+		member, err := s.State.Member(guildID, user.DiscordID)
+		if err != nil {
+			if member, err = s.GuildMember(guildID, user.DiscordID); err != nil {
+				// they may have left the guild; the caller falls back to the name they registered with
+				cache[user.DiscordID] = ""
+				return ""
+			}
+			// keep them for the next render, which fails harmlessly if the guild itself isn't in the state
+			member.GuildID = guildID
+			_ = s.State.MemberAdd(member)
+		}
+
+		name := member.User.Username
+		if member.Nick != "" {
+			name = member.Nick
+		}
+		cache[user.DiscordID] = name
+
+		return name
+	}
+}
+
+// guildMembers lists every member of a guild, a page at a time
+func guildMembers(s *discordgo.Session, guildID string) ([]*discordgo.Member, error) {
+	var members []*discordgo.Member
+	after := ""
+	for {
+		page, err := s.GuildMembers(guildID, after, 1000)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, page...)
+		if len(page) < 1000 {
+			return members, nil
+		}
+		after = page[len(page)-1].User.ID
+	}
+}
+
+// memberIDsByUsername maps guild members' usernames to their Discord IDs. Usernames more than one member goes by, which
+// Discord allowed while discriminators set them apart, are left out since there's no telling which of them registered.
+func memberIDsByUsername(members []*discordgo.Member) map[string]string {
+	usernames := make(map[string]string)
+	shared := make(map[string]bool)
+	for _, member := range members {
+		if _, ok := usernames[member.User.Username]; ok {
+			shared[member.User.Username] = true
+			continue
+		}
+		usernames[member.User.Username] = member.User.ID
+	}
+
+	for username := range shared {
+		logrus.Warnf("--> more than one guild member is named %s, so no Discord ID is backfilled for them", username)
+		delete(usernames, username)
+	}
+
+	return usernames
+}
+
+// backfillDiscordIDs fills in the Discord user ID of users who registered before it was recorded,
+// matching them to guild members by the username they registered with
+func backfillDiscordIDs(ctx context.Context, s *discordgo.Session, store datastore.Database, guildID string) error {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	users, err := tx.GetUsersWithoutDiscordID()
+	if err != nil || len(users) == 0 {
+		return err
+	}
+
+	members, err := guildMembers(s, guildID)
+	if err != nil {
+		return err
+	}
+	usernames := memberIDsByUsername(members)
+
+	for _, user := range users {
+		discordID, ok := usernames[user.DiscordName]
+		if !ok {
+			logrus.WithField("egg_inc_id", user.EggIncID).Warnf("--> no guild member named %s to backfill a Discord ID from", user.DiscordName)
+			continue
+		}
+
+		user.DiscordID = discordID
+		if _, err = tx.CreateOrUpdateUser(user); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// guildUsersBackfill names backfillGuildUsers in the backfills table
+const guildUsersBackfill = "guild users"
+
+// backfillGuildUsers registers users from before guilds were tracked in the one guild the bot used to serve. It only
+// runs once, since anyone left without a guild afterwards registered from a DM and belongs to no guild in particular.
+func backfillGuildUsers(ctx context.Context, store datastore.Database, guildID string) error {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	done, err := tx.HasBackfilled(guildUsersBackfill)
+	if err != nil || done {
+		return err
+	}
+
+	users, err := tx.GetUsersWithoutGuild()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err = tx.AddUserToGuild(guildID, user.EggIncID); err != nil {
+			return err
+		}
+	}
+	if len(users) > 0 {
+		logrus.WithField("guild_id", guildID).Infof("--> assigned %d existing users to the configured guild", len(users))
+	}
+
+	err = tx.MarkBackfilled(guildUsersBackfill)
+	return err
+}
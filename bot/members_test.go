@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"context"
+	"egg/datastore"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisplayNamesFromState(t *testing.T) {
+	// a session without a token, so any call to Discord would fail
+	s := &discordgo.Session{State: discordgo.NewState(), Ratelimiter: discordgo.NewRatelimiter()}
+	require.NoError(t, s.State.GuildAdd(&discordgo.Guild{
+		ID: "guild",
+		Members: []*discordgo.Member{
+			{GuildID: "guild", User: &discordgo.User{ID: "1111", Username: "krohmag"}, Nick: "Krohmag the Great"},
+			{GuildID: "guild", User: &discordgo.User{ID: "2222", Username: "quiet"}},
+		},
+	}))
+
+	names := displayNames(s, "guild")
+	require.Equal(t, "Krohmag the Great", names(datastore.User{DiscordID: "1111"}))
+	require.Equal(t, "quiet", names(datastore.User{DiscordID: "2222"}))
+	require.Empty(t, names(datastore.User{DiscordName: "legacy"}))
+}
+
+func TestMemberIDsByUsername(t *testing.T) {
+	usernames := memberIDsByUsername([]*discordgo.Member{
+		{User: &discordgo.User{ID: "1111", Username: "krohmag"}},
+		{User: &discordgo.User{ID: "2222", Username: "twin"}},
+		{User: &discordgo.User{ID: "3333", Username: "twin"}},
+	})
+	require.Equal(t, map[string]string{"krohmag": "1111"}, usernames)
+}
+
+func TestBackfillGuildUsersOnce(t *testing.T) {
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+	store := datastore.Database{DB: db}
+	ctx := context.Background()
+
+	register := func(eggIncID string) {
+		tx, err := store.Transaction(ctx)
+		require.NoError(t, err)
+		_, err = tx.CreateOrUpdateUser(datastore.User{EggIncID: eggIncID, DiscordName: eggIncID, GameAccountName: eggIncID})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+	}
+	guildUsers := func() datastore.Users {
+		tx, err := store.Transaction(ctx)
+		require.NoError(t, err)
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		users, err := tx.GetGuildUsers("guild")
+		require.NoError(t, err)
+		return users
+	}
+
+	register("EI1111")
+	require.NoError(t, backfillGuildUsers(ctx, store, "guild"))
+	require.Len(t, guildUsers(), 1)
+
+	// someone registering from a DM later stays out of the configured guild across restarts
+	register("EI2222")
+	require.NoError(t, backfillGuildUsers(ctx, store, "guild"))
+	users := guildUsers()
+	require.Len(t, users, 1)
+	require.Equal(t, "EI1111", users[0].EggIncID)
+}
@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"context"
+	"egg/api"
+	"egg/datastore"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// missionAlertInterval is how often stored missions are checked for ships that have landed
+	missionAlertInterval = time.Minute
+	// missionRetention is how long landed missions are kept around after they've been announced
+	missionRetention = 7 * 24 * time.Hour
+)
+
+// Where a mission alert is sent, as offered by the /missionalerts command
+const (
+	missionAlertDM   = "dm"
+	missionAlertHere = "here"
+	missionAlertOff  = "off"
+)
+
+// landingAlert is a message telling someone one of their ships has landed
+type landingAlert struct {
+	DiscordID string
+	// ChannelID is where to ping them, or empty to send a DM
+	ChannelID string
+	Content   string
+}
+
+// dueLandingAlerts works out who to tell about ships that have landed by now. Every due mission is returned so it
+// can be marked as announced, including those whose owners haven't asked to hear about it.
+func dueLandingAlerts(ctx context.Context, store datastore.Database, now time.Time) ([]landingAlert, []string, error) {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	missions, err := tx.GetDueMissions(now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var alerts []landingAlert
+	identifiers := make([]string, 0, len(missions))
+	for _, mission := range missions {
+		identifiers = append(identifiers, mission.Identifier)
+
+		user, getErr := tx.GetUserByEggIncUserID(mission.EggIncID)
+		if getErr != nil || user.DiscordID == "" {
+			continue
+		}
+		alert, getErr := tx.GetMissionAlert(user.DiscordID)
+		if getErr != nil {
+			continue
+		}
+
+		content := fmt.Sprintf(":rocket: <@%s> your %s (%s) on %s has landed :rocket:", user.DiscordID,
+			api.ShipName(api.MissionInfo_Spaceship(mission.Ship)), api.DurationName(api.MissionInfo_DurationType(mission.DurationType)),
+			user.GameAccountName)
+		alerts = append(alerts, landingAlert{DiscordID: user.DiscordID, ChannelID: alert.ChannelID, Content: content})
+	}
+
+	return alerts, identifiers, nil
+}
+
+// announceLandings sends an alert for every ship that has landed since the last check, marking each as announced
+// whether or not the message could be sent so nobody gets pinged twice
+func announceLandings(ctx context.Context, s *discordgo.Session, store datastore.Database, now time.Time) error {
+	alerts, identifiers, err := dueLandingAlerts(ctx, store, now)
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		channelID := alert.ChannelID
+		if channelID == "" {
+			channel, dmErr := s.UserChannelCreate(alert.DiscordID)
+			if dmErr != nil {
+				logrus.WithError(dmErr).WithField("discord_id", alert.DiscordID).Warn("--> failed to open a DM for a mission alert")
+				continue
+			}
+			channelID = channel.ID
+		}
+		if _, sendErr := s.ChannelMessageSend(channelID, alert.Content); sendErr != nil {
+			logrus.WithError(sendErr).WithField("discord_id", alert.DiscordID).Warn("--> failed to send a mission alert")
+		}
+	}
+
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = tx.MarkMissionsNotified(identifiers); err != nil {
+		return err
+	}
+	err = tx.DeleteMissionsBefore(now.Add(-missionRetention))
+	return err
+}
+
+// setMissionAlert turns a Discord user's mission alerts on, sent by DM or to a channel, or off
+func setMissionAlert(ctx context.Context, store datastore.Database, discordID, where, channelID string) error {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	switch where {
+	case missionAlertOff:
+		err = tx.DeleteMissionAlert(discordID)
+	case missionAlertHere:
+		_, err = tx.CreateOrUpdateMissionAlert(datastore.MissionAlert{DiscordID: discordID, ChannelID: channelID})
+	default:
+		_, err = tx.CreateOrUpdateMissionAlert(datastore.MissionAlert{DiscordID: discordID})
+	}
+	return err
+}
+
+// RunMissionAlerts announces ships landing from the stored mission times until ctx is cancelled, so nobody's backup
+// has to be fetched just to see whether a ship is back
+func (b *Bot) RunMissionAlerts(ctx context.Context) {
+	ticker := time.NewTicker(missionAlertInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := announceLandings(ctx, b.Session, b.store, now); err != nil {
+				logrus.WithError(err).Warn("--> failed to announce mission landings")
+			}
+		}
+	}
+}
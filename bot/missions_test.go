@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"context"
+	"egg/datastore"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDueLandingAlerts(t *testing.T) {
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+	store := datastore.Database{DB: db}
+	ctx := context.Background()
+
+	now := time.Now()
+	tx, err := store.Transaction(ctx)
+	require.NoError(t, err)
+	for _, user := range []datastore.User{
+		{EggIncID: "EI1111", DiscordID: "1111", DiscordName: "krohmag", GameAccountName: "akroh"},
+		{EggIncID: "EI2222", DiscordID: "2222", DiscordName: "quiet", GameAccountName: "quiet"},
+	} {
+		_, err = tx.CreateOrUpdateUser(user)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.SaveMissions(datastore.Missions{
+		{Identifier: "landed", EggIncID: "EI1111", Ship: 9, DurationType: 2, ReturnsAt: now.Add(-time.Minute)},
+		{Identifier: "flying", EggIncID: "EI1111", Ship: 0, ReturnsAt: now.Add(time.Hour)},
+		{Identifier: "not-opted-in", EggIncID: "EI2222", ReturnsAt: now.Add(-time.Minute)},
+	}))
+	require.NoError(t, tx.Commit())
+
+	require.NoError(t, setMissionAlert(ctx, store, "1111", missionAlertHere, "channel"))
+
+	alerts, identifiers, err := dueLandingAlerts(ctx, store, now)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"landed", "not-opted-in"}, identifiers)
+	require.Len(t, alerts, 1)
+	require.Equal(t, "channel", alerts[0].ChannelID)
+	require.Equal(t, ":rocket: <@1111> your Henerprise (Extended) on akroh has landed :rocket:", alerts[0].Content)
+
+	require.NoError(t, setMissionAlert(ctx, store, "1111", missionAlertDM, "channel"))
+	alerts, _, err = dueLandingAlerts(ctx, store, now)
+	require.NoError(t, err)
+	require.Empty(t, alerts[0].ChannelID)
+
+	require.NoError(t, setMissionAlert(ctx, store, "1111", missionAlertOff, ""))
+	alerts, _, err = dueLandingAlerts(ctx, store, now)
+	require.NoError(t, err)
+	require.Empty(t, alerts)
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"egg/datastore"
+	"egg/format"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// numberFormat is how a Discord user likes numbers written, the default if they've never said
+func numberFormat(ctx context.Context, store datastore.Database, discordID string) format.Options {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("--> failed to look up number format, using the default")
+		return format.Default
+	}
+	defer func() {
+		_ = tx.Commit()
+	}()
+
+	preference, err := tx.GetPreference(discordID)
+	if err != nil {
+		return format.Default
+	}
+	return preferredNumbers(preference)
+}
+
+// preferredNumbers reads the number format out of a user's preferences, falling back to the default for anything unset
+func preferredNumbers(preference datastore.Preference) format.Options {
+	notation := format.Notation(preference.NumberNotation)
+	if !notation.Valid() {
+		return format.Default
+	}
+	return format.Options{Notation: notation, Precision: preference.NumberPrecision}
+}
+
+// setNumberFormat saves how a Discord user likes numbers written, keeping whatever they don't change
+func setNumberFormat(ctx context.Context, store datastore.Database, discordID string, notation *format.Notation, precision *int) (format.Options, error) {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return format.Options{}, err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	options := format.Default
+	if preference, getErr := tx.GetPreference(discordID); getErr == nil {
+		options = preferredNumbers(preference)
+	}
+	if notation != nil {
+		if !notation.Valid() {
+			err = errors.New(fmt.Sprintf("'%s' isn't a notation I know", *notation))
+			return format.Options{}, err
+		}
+		options.Notation = *notation
+	}
+	if precision != nil {
+		if *precision < 0 || *precision > format.MaxPrecision {
+			err = errors.New(fmt.Sprintf("precision has to be between 0 and %d decimal places", format.MaxPrecision))
+			return format.Options{}, err
+		}
+		options.Precision = *precision
+	}
+
+	if _, err = tx.CreateOrUpdatePreference(datastore.Preference{
+		DiscordID:       discordID,
+		NumberNotation:  string(options.Notation),
+		NumberPrecision: options.Precision,
+	}); err != nil {
+		return format.Options{}, err
+	}
+
+	return options, nil
+}
+
+// notationChoices offers every notation in the /numbers command
+func notationChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(format.Notations))
+	for _, notation := range format.Notations {
+		// show what each notation looks like, since the names alone mean little to most players
+		example := format.Options{Notation: notation, Precision: format.DefaultPrecision}.Number(1.5e18)
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: fmt.Sprintf("%s (%s)", notation, example), Value: string(notation)})
+	}
+	return choices
+}
+
+// precisionChoices offers every precision in the /numbers command, since this version of discordgo can't set a minimum or maximum
+func precisionChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, format.MaxPrecision+1)
+	for precision := 0; precision <= format.MaxPrecision; precision++ {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: fmt.Sprintf("%d", precision), Value: precision})
+	}
+	return choices
+}
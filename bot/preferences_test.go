@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"context"
+	"egg/datastore"
+	"egg/format"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumberFormat(t *testing.T) {
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+	store := datastore.Database{DB: db}
+	ctx := context.Background()
+
+	require.Equal(t, format.Default, numberFormat(ctx, store, "1234"))
+
+	scientific := format.Scientific
+	numbers, err := setNumberFormat(ctx, store, "1234", &scientific, nil)
+	require.NoError(t, err)
+	require.Equal(t, format.Options{Notation: format.Scientific, Precision: format.DefaultPrecision}, numbers)
+
+	// changing only the precision keeps the notation
+	precision := 1
+	_, err = setNumberFormat(ctx, store, "1234", nil, &precision)
+	require.NoError(t, err)
+	require.Equal(t, format.Options{Notation: format.Scientific, Precision: 1}, numberFormat(ctx, store, "1234"))
+
+	unknown := format.Notation("roman")
+	_, err = setNumberFormat(ctx, store, "1234", &unknown, nil)
+	require.Error(t, err)
+
+	precision = format.MaxPrecision + 1
+	_, err = setNumberFormat(ctx, store, "1234", nil, &precision)
+	require.Error(t, err)
+	require.Equal(t, format.Options{Notation: format.Scientific, Precision: 1}, numberFormat(ctx, store, "1234"))
+}
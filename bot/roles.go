@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"context"
+	"egg/api"
+	"egg/datastore"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// maxMessageLength is the most Discord allows in a message's content
+const maxMessageLength = 2000
+
+// roleChange is how one member's rank roles need to change
+type roleChange struct {
+	DiscordID string
+	Name      string
+	// Add is the rank role the member should gain, if any
+	Add string
+	// Remove is every rank role the member holds but no longer should
+	Remove []string
+}
+
+// rolePlan is everything syncing rank roles would change in a guild
+type rolePlan struct {
+	// Create is every rank role that's needed but doesn't exist in the guild yet
+	Create  []string
+	Changes []roleChange
+	// Failed counts the role additions and removals that couldn't be made once the plan was applied
+	Failed int
+}
+
+// String describes the plan for the dry run command
+func (p rolePlan) String() string {
+	if len(p.Create) == 0 && len(p.Changes) == 0 {
+		return "Everyone already has the right rank role"
+	}
+
+	lines := make([]string, 0, len(p.Changes)+1)
+	if len(p.Create) > 0 {
+		lines = append(lines, fmt.Sprintf("Create roles: %s", strings.Join(p.Create, ", ")))
+	}
+	for _, change := range p.Changes {
+		var edits []string
+		if change.Add != "" {
+			edits = append(edits, "+"+change.Add)
+		}
+		for _, name := range change.Remove {
+			edits = append(edits, "-"+name)
+		}
+		lines = append(lines, fmt.Sprintf("<@%s>: %s", change.DiscordID, strings.Join(edits, ", ")))
+	}
+
+	// keep within a single message, noting how much didn't fit
+	var out strings.Builder
+	for n, line := range lines {
+		more := fmt.Sprintf("\n...and %d more", len(lines)-n)
+		if out.Len()+len(line)+1+len(more) > maxMessageLength {
+			out.WriteString(more)
+			break
+		}
+		if n > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(line)
+	}
+
+	return out.String()
+}
+
+// planRankRoles works out how members' rank roles differ from the ranks they've earned. ranks maps
+// Discord user IDs to the rank they should hold; members without one lose any rank role they have.
+func planRankRoles(members []*discordgo.Member, roles []*discordgo.Role, ranks map[string]string) rolePlan {
+	isRank := make(map[string]bool, len(api.FarmerRanks))
+	for _, name := range api.FarmerRanks {
+		isRank[name] = true
+	}
+
+	rankRoles := make(map[string]string)
+	exists := make(map[string]bool)
+	for _, role := range roles {
+		if isRank[role.Name] {
+			rankRoles[role.ID] = role.Name
+			exists[role.Name] = true
+		}
+	}
+
+	var plan rolePlan
+	needed := make(map[string]bool)
+	for _, member := range members {
+		if member.User == nil || member.User.Bot {
+			continue
+		}
+
+		want := ranks[member.User.ID]
+		change := roleChange{DiscordID: member.User.ID, Name: member.User.Username, Add: want}
+		for _, roleID := range member.Roles {
+			name, ok := rankRoles[roleID]
+			switch {
+			case !ok:
+			case name == want:
+				change.Add = ""
+			default:
+				change.Remove = append(change.Remove, name)
+			}
+		}
+
+		if change.Add == "" && len(change.Remove) == 0 {
+			continue
+		}
+		if change.Add != "" && !exists[change.Add] {
+			needed[change.Add] = true
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	for _, name := range api.FarmerRanks {
+		if needed[name] {
+			plan.Create = append(plan.Create, name)
+		}
+	}
+	sort.Slice(plan.Changes, func(i, j int) bool {
+		return plan.Changes[i].Name < plan.Changes[j].Name
+	})
+
+	return plan
+}
+
+// applyRankRoles creates any missing rank roles and then adds and removes members' roles as planned.
+// Members whose roles can't be changed, e.g. because they outrank the bot, are logged and skipped, and
+// counted in the number of failed changes returned.
+func applyRankRoles(s *discordgo.Session, guildID string, roles []*discordgo.Role, plan rolePlan) (int, error) {
+	roleIDs := make(map[string]string, len(roles))
+	for _, role := range roles {
+		roleIDs[role.Name] = role.ID
+	}
+
+	for _, name := range plan.Create {
+		role, err := s.GuildRoleCreate(guildID)
+		if err != nil {
+			return 0, err
+		}
+		if role, err = s.GuildRoleEdit(guildID, role.ID, name, 0, false, 0, false); err != nil {
+			return 0, err
+		}
+		roleIDs[name] = role.ID
+	}
+
+	failed := 0
+	for _, change := range plan.Changes {
+		log := logrus.WithField("guild_id", guildID).WithField("discord_id", change.DiscordID)
+		if change.Add != "" {
+			if err := s.GuildMemberRoleAdd(guildID, change.DiscordID, roleIDs[change.Add]); err != nil {
+				log.WithError(err).Warnf("--> failed to add rank role %s", change.Add)
+				failed++
+			}
+		}
+		for _, name := range change.Remove {
+			if err := s.GuildMemberRoleRemove(guildID, change.DiscordID, roleIDs[name]); err != nil {
+				log.WithError(err).Warnf("--> failed to remove rank role %s", name)
+				failed++
+			}
+		}
+	}
+
+	return failed, nil
+}
+
+// earnedRanks is the highest farmer rank across each Discord user's accounts registered in a guild
+func earnedRanks(ctx context.Context, store datastore.Database, guildID string) (map[string]string, error) {
+	tx, err := store.Transaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err == nil {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	users, err := tx.GetGuildUsers(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make(map[string]string)
+	levels := make(map[string]int)
+	for _, user := range users {
+		if user.DiscordID == "" {
+			continue
+		}
+		rank := api.GetRank(user)
+		if level, ok := levels[user.DiscordID]; !ok || rank.Level > level {
+			levels[user.DiscordID] = rank.Level
+			ranks[user.DiscordID] = rank.Name
+		}
+	}
+
+	return ranks, nil
+}
+
+// syncRankRoles brings the rank roles of members in a guild in line with their farmer rank, or with
+// dryRun only works out what would change
+func syncRankRoles(ctx context.Context, s *discordgo.Session, store datastore.Database, guildID string, members []*discordgo.Member, dryRun bool) (rolePlan, error) {
+	ranks, err := earnedRanks(ctx, store, guildID)
+	if err != nil {
+		return rolePlan{}, err
+	}
+
+	roles, err := s.GuildRoles(guildID)
+	if err != nil {
+		return rolePlan{}, err
+	}
+
+	plan := planRankRoles(members, roles, ranks)
+	if dryRun {
+		return plan, nil
+	}
+
+	plan.Failed, err = applyRankRoles(s, guildID, roles, plan)
+	return plan, err
+}
+
+// syncGuildRankRoles syncs the rank roles of every member of a guild
+func syncGuildRankRoles(ctx context.Context, s *discordgo.Session, store datastore.Database, guildID string, dryRun bool) (rolePlan, error) {
+	members, err := guildMembers(s, guildID)
+	if err != nil {
+		return rolePlan{}, err
+	}
+	return syncRankRoles(ctx, s, store, guildID, members, dryRun)
+}
+
+// SyncRankRoles syncs rank roles in every guild the bot is in, when rank roles are turned on
+func (b *Bot) SyncRankRoles(ctx context.Context) {
+	if !b.Config().RankRoles {
+		return
+	}
+
+	for _, guild := range b.Session.State.Guilds {
+		plan, err := syncGuildRankRoles(ctx, b.Session, b.store, guild.ID, false)
+		if err != nil {
+			logrus.WithError(err).WithField("guild_id", guild.ID).Warn("--> failed to sync rank roles")
+			continue
+		}
+		log := logrus.WithField("guild_id", guild.ID)
+		if plan.Failed > 0 {
+			log.Warnf("--> synced rank roles for %d members, %d role changes failed", len(plan.Changes), plan.Failed)
+			continue
+		}
+		log.Infof("--> synced rank roles for %d members", len(plan.Changes))
+	}
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/require"
+)
+
+func member(id, name string, roles ...string) *discordgo.Member {
+	return &discordgo.Member{User: &discordgo.User{ID: id, Username: name}, Roles: roles}
+}
+
+func TestPlanRankRoles(t *testing.T) {
+	roles := []*discordgo.Role{
+		{ID: "r-moderator", Name: "Moderator"},
+		{ID: "r-kilo1", Name: "Kilofarmer I"},
+		{ID: "r-kilo2", Name: "Kilofarmer II"},
+	}
+	members := []*discordgo.Member{
+		member("1", "climber", "r-moderator", "r-kilo1"),
+		member("2", "settled", "r-kilo2"),
+		member("3", "newcomer"),
+		member("4", "departed", "r-kilo1", "r-moderator"),
+		member("5", "unregistered", "r-moderator"),
+		{User: &discordgo.User{ID: "6", Username: "robot", Bot: true}, Roles: []string{"r-kilo1"}},
+	}
+	ranks := map[string]string{
+		"1": "Kilofarmer II",
+		"2": "Kilofarmer II",
+		"3": "Megafarmer I",
+	}
+
+	plan := planRankRoles(members, roles, ranks)
+	require.Equal(t, []string{"Megafarmer I"}, plan.Create)
+	require.Equal(t, []roleChange{
+		{DiscordID: "1", Name: "climber", Add: "Kilofarmer II", Remove: []string{"Kilofarmer I"}},
+		{DiscordID: "4", Name: "departed", Remove: []string{"Kilofarmer I"}},
+		{DiscordID: "3", Name: "newcomer", Add: "Megafarmer I"},
+	}, plan.Changes)
+
+	require.Equal(t, "Create roles: Megafarmer I\n<@1>: +Kilofarmer II, -Kilofarmer I\n<@4>: -Kilofarmer I\n<@3>: +Megafarmer I", plan.String())
+	require.Equal(t, "Everyone already has the right rank role", rolePlan{}.String())
+}
+
+func TestRolePlanStringFitsInAMessage(t *testing.T) {
+	var plan rolePlan
+	for n := 0; n < 500; n++ {
+		plan.Changes = append(plan.Changes, roleChange{DiscordID: "123456789012345678", Add: "Kilofarmer II"})
+	}
+
+	out := plan.String()
+	require.LessOrEqual(t, len(out), maxMessageLength)
+	require.Contains(t, out, "more")
+}
@@ -1,34 +1,131 @@
 package config
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-
-	"github.com/sirupsen/logrus"
+	"sort"
+	"time"
 )
 
-var (
-	Config Bot
+const (
+	defaultRefreshIntervalMinutes = 30
+	defaultRefreshConcurrency     = 4
+	// minSnapshotRetentionDays covers the longest /gainers period, the month
+	minSnapshotRetentionDays = 31
 )
 
 // Bot is the values required to run the bot
 type Bot struct {
-	Token   string `json:"botToken"`
-	GuildID string `json:"guildID"`
+	Token string `json:"botToken" yaml:"botToken"`
+	// GuildID is the guild users registered before multi-guild support are assigned to
+	GuildID string `json:"guildID" yaml:"guildID"`
+	// GlobalCommands registers commands once for every guild instead of separately in each guild the bot joins
+	GlobalCommands bool `json:"globalCommands" yaml:"globalCommands"`
+	// MemberIntent subscribes to member events, which keeps nicknames current without asking Discord. It needs the
+	// privileged Server Members Intent enabled in the developer portal, or the bot can't connect.
+	MemberIntent bool `json:"memberIntent" yaml:"memberIntent"`
+
+	RefreshIntervalMinutes int `json:"refreshIntervalMinutes" yaml:"refreshIntervalMinutes"`
+	RefreshConcurrency     int `json:"refreshConcurrency" yaml:"refreshConcurrency"`
+	// SnapshotRetentionDays prunes progress snapshots older than this many days after every refresh. Zero keeps them forever.
+	SnapshotRetentionDays int `json:"snapshotRetentionDays" yaml:"snapshotRetentionDays"`
+
+	// RankRoles gives every registered member a Discord role named after their farmer rank
+	RankRoles bool `json:"rankRoles" yaml:"rankRoles"`
+	// LeaderboardChannels pins the leaderboard of each guild ID to a channel ID, taking precedence over /setboard
+	LeaderboardChannels map[string]string `json:"leaderboardChannels" yaml:"leaderboardChannels"`
+	// EventChannels posts new events and sales in each guild ID to a channel ID
+	EventChannels map[string]string `json:"eventChannels" yaml:"eventChannels"`
+
+	Database Database `json:"database" yaml:"database"`
+	API      API      `json:"api" yaml:"api"`
+}
+
+// API is the values used to talk to the Egg, Inc. API
+type API struct {
+	// BaseURL is the root of the Egg, Inc. API. Defaults to the real one.
+	BaseURL string `json:"baseURL" yaml:"baseURL"`
+	// ClientVersion is the game client version reported to the API. Defaults to the version the bot was built against.
+	ClientVersion  int `json:"clientVersion" yaml:"clientVersion"`
+	TimeoutSeconds int `json:"timeoutSeconds" yaml:"timeoutSeconds"`
+}
+
+// Timeout is how long a single request to the Egg, Inc. API may take, or zero for the client default
+func (a API) Timeout() time.Duration {
+	return time.Duration(a.TimeoutSeconds) * time.Second
+}
+
+// Database is the values required to connect to the datastore
+type Database struct {
+	// URL is a Postgres URL or DSN, or one of "sqlite-file" and "sqlite-in-memory". Defaults to "sqlite-file".
+	URL string `json:"url" yaml:"url"`
+	// SQLitePath is where "sqlite-file" keeps its database. Defaults to "./db.sqlite".
+	SQLitePath string `json:"sqlitePath" yaml:"sqlitePath"`
+
+	MaxOpenConns           int `json:"maxOpenConns" yaml:"maxOpenConns"`
+	MaxIdleConns           int `json:"maxIdleConns" yaml:"maxIdleConns"`
+	ConnMaxLifetimeMinutes int `json:"connMaxLifetimeMinutes" yaml:"connMaxLifetimeMinutes"`
+
+	// LogTransactions logs every SQL statement, which is noisy but handy when debugging
+	LogTransactions bool `json:"logTransactions" yaml:"logTransactions"`
+}
+
+// ConnMaxLifetime is how long a pooled connection may be reused, or zero for forever
+func (d Database) ConnMaxLifetime() time.Duration {
+	return time.Duration(d.ConnMaxLifetimeMinutes) * time.Minute
+}
+
+// RefreshInterval is how often registered users' backups are re-fetched
+func (b Bot) RefreshInterval() time.Duration {
+	if b.RefreshIntervalMinutes <= 0 {
+		return defaultRefreshIntervalMinutes * time.Minute
+	}
+	return time.Duration(b.RefreshIntervalMinutes) * time.Minute
 }
 
-// LoadConfigFromFile loads configuration from a file into memory
-func LoadConfigFromFile(filename string) error {
-	logrus.Info(fmt.Sprintf("--> reading config file: %s ...", filename))
-	file, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
+// Concurrency is how many backups are fetched at once while refreshing
+func (b Bot) Concurrency() int {
+	if b.RefreshConcurrency <= 0 {
+		return defaultRefreshConcurrency
 	}
+	return b.RefreshConcurrency
+}
+
+// SnapshotRetention is how long progress snapshots are kept, or zero to keep them forever
+func (b Bot) SnapshotRetention() time.Duration {
+	return time.Duration(b.SnapshotRetentionDays) * 24 * time.Hour
+}
 
-	if err = json.Unmarshal(file, &Config); err != nil {
-		return err
+// Reload returns the running configuration updated with the settings from next that can change without a restart:
+// the refresh interval and concurrency, snapshot retention, rank roles and the leaderboard and event channels. It also
+// names any other settings that differ, which only take effect once the bot is restarted.
+func (b Bot) Reload(next Bot) (Bot, []string) {
+	reloaded := b
+	reloaded.RefreshIntervalMinutes = next.RefreshIntervalMinutes
+	reloaded.RefreshConcurrency = next.RefreshConcurrency
+	reloaded.SnapshotRetentionDays = next.SnapshotRetentionDays
+	reloaded.RankRoles = next.RankRoles
+	reloaded.LeaderboardChannels = next.LeaderboardChannels
+	reloaded.EventChannels = next.EventChannels
+
+	var needRestart []string
+	if next.Token != b.Token {
+		needRestart = append(needRestart, "botToken")
+	}
+	if next.GuildID != b.GuildID {
+		needRestart = append(needRestart, "guildID")
+	}
+	if next.GlobalCommands != b.GlobalCommands {
+		needRestart = append(needRestart, "globalCommands")
+	}
+	if next.MemberIntent != b.MemberIntent {
+		needRestart = append(needRestart, "memberIntent")
+	}
+	if next.Database != b.Database {
+		needRestart = append(needRestart, "database")
+	}
+	if next.API != b.API {
+		needRestart = append(needRestart, "api")
 	}
+	sort.Strings(needRestart)
 
-	return nil
+	return reloaded, needRestart
 }
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	running := Bot{
+		Token:                  "token",
+		GuildID:                "111",
+		RefreshIntervalMinutes: 30,
+		RefreshConcurrency:     4,
+		Database:               Database{URL: "sqlite-file", SQLitePath: "./db.sqlite"},
+	}
+
+	t.Run("applies reloadable settings", func(t *testing.T) {
+		next := running
+		next.RefreshIntervalMinutes = 5
+		next.RefreshConcurrency = 8
+		next.SnapshotRetentionDays = 90
+		next.LeaderboardChannels = map[string]string{"111": "222"}
+		next.EventChannels = map[string]string{"111": "444"}
+
+		reloaded, needRestart := running.Reload(next)
+		require.Empty(t, needRestart)
+		require.Equal(t, 5*time.Minute, reloaded.RefreshInterval())
+		require.Equal(t, 8, reloaded.Concurrency())
+		require.Equal(t, 90*24*time.Hour, reloaded.SnapshotRetention())
+		require.Equal(t, "222", reloaded.LeaderboardChannels["111"])
+		require.Equal(t, "444", reloaded.EventChannels["111"])
+	})
+
+	t.Run("keeps settings that need a restart", func(t *testing.T) {
+		next := running
+		next.Token = "new-token"
+		next.GuildID = "333"
+		next.MemberIntent = true
+		next.Database.URL = "sqlite-in-memory"
+		next.API.BaseURL = "http://localhost"
+
+		reloaded, needRestart := running.Reload(next)
+		require.Equal(t, []string{"api", "botToken", "database", "guildID", "memberIntent"}, needRestart)
+		require.Equal(t, "token", reloaded.Token)
+		require.Equal(t, "111", reloaded.GuildID)
+		require.Equal(t, "sqlite-file", reloaded.Database.URL)
+		require.Empty(t, reloaded.API.BaseURL)
+	})
+}
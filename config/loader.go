@@ -0,0 +1,265 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultConfigFile is read when EGG_CONFIG_FILE doesn't name another file
+	DefaultConfigFile = "./config.json"
+	// ConfigFileEnv names the config file to read instead of DefaultConfigFile
+	ConfigFileEnv = "EGG_CONFIG_FILE"
+
+	redacted = "REDACTED"
+)
+
+// envVar is a setting that can be overridden from the environment, or from a file named by <Name>_FILE
+type envVar struct {
+	Name string
+	Set  func(b *Bot, value string) error
+}
+
+var envVars = []envVar{
+	{Name: "EGG_BOT_TOKEN", Set: func(b *Bot, v string) error { b.Token = v; return nil }},
+	{Name: "EGG_GUILD_ID", Set: func(b *Bot, v string) error { b.GuildID = v; return nil }},
+	{Name: "EGG_GLOBAL_COMMANDS", Set: func(b *Bot, v string) error { return parseBool(v, &b.GlobalCommands) }},
+	{Name: "EGG_MEMBER_INTENT", Set: func(b *Bot, v string) error { return parseBool(v, &b.MemberIntent) }},
+	{Name: "EGG_REFRESH_INTERVAL_MINUTES", Set: func(b *Bot, v string) error { return parseInt(v, &b.RefreshIntervalMinutes) }},
+	{Name: "EGG_REFRESH_CONCURRENCY", Set: func(b *Bot, v string) error { return parseInt(v, &b.RefreshConcurrency) }},
+	{Name: "EGG_SNAPSHOT_RETENTION_DAYS", Set: func(b *Bot, v string) error { return parseInt(v, &b.SnapshotRetentionDays) }},
+	{Name: "EGG_RANK_ROLES", Set: func(b *Bot, v string) error { return parseBool(v, &b.RankRoles) }},
+	{Name: "EGG_DATABASE_URL", Set: func(b *Bot, v string) error { b.Database.URL = v; return nil }},
+	{Name: "EGG_DATABASE_SQLITE_PATH", Set: func(b *Bot, v string) error { b.Database.SQLitePath = v; return nil }},
+	{Name: "EGG_DATABASE_MAX_OPEN_CONNS", Set: func(b *Bot, v string) error { return parseInt(v, &b.Database.MaxOpenConns) }},
+	{Name: "EGG_DATABASE_MAX_IDLE_CONNS", Set: func(b *Bot, v string) error { return parseInt(v, &b.Database.MaxIdleConns) }},
+	{Name: "EGG_DATABASE_CONN_MAX_LIFETIME_MINUTES", Set: func(b *Bot, v string) error { return parseInt(v, &b.Database.ConnMaxLifetimeMinutes) }},
+	{Name: "EGG_DATABASE_LOG_TRANSACTIONS", Set: func(b *Bot, v string) error { return parseBool(v, &b.Database.LogTransactions) }},
+	{Name: "EGG_API_BASE_URL", Set: func(b *Bot, v string) error { b.API.BaseURL = v; return nil }},
+	{Name: "EGG_API_CLIENT_VERSION", Set: func(b *Bot, v string) error { return parseInt(v, &b.API.ClientVersion) }},
+	{Name: "EGG_API_TIMEOUT_SECONDS", Set: func(b *Bot, v string) error { return parseInt(v, &b.API.TimeoutSeconds) }},
+}
+
+// Defaults is the configuration before any file or environment variable is applied
+func Defaults() Bot {
+	return Bot{
+		RefreshIntervalMinutes: defaultRefreshIntervalMinutes,
+		RefreshConcurrency:     defaultRefreshConcurrency,
+		Database: Database{
+			URL:        "sqlite-file",
+			SQLitePath: "./db.sqlite",
+		},
+	}
+}
+
+// Load builds the configuration in layers, each overriding the last: defaults, then the config file
+// (JSON, or YAML for .yaml and .yml files), then EGG_* environment variables, then files named by
+// EGG_*_FILE variables for secrets mounted from elsewhere. A missing config file is skipped so that
+// the bot can be configured from the environment alone.
+func Load(filename string) (Bot, error) {
+	return load(filename, os.LookupEnv)
+}
+
+// LoadDatabase builds the configuration in the same layers as Load, but only requires the settings the datastore
+// needs, so the migrate command runs on hosts that have database credentials and no bot token
+func LoadDatabase(filename string) (Bot, error) {
+	return loadDatabase(filename, os.LookupEnv)
+}
+
+// ConfigFile is the config file named by EGG_CONFIG_FILE, or DefaultConfigFile
+func ConfigFile() string {
+	if filename, ok := os.LookupEnv(ConfigFileEnv); ok && filename != "" {
+		return filename
+	}
+	return DefaultConfigFile
+}
+
+func load(filename string, lookupEnv func(string) (string, bool)) (Bot, error) {
+	cfg, err := loadLayers(filename, lookupEnv)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+func loadDatabase(filename string, lookupEnv func(string) (string, bool)) (Bot, error) {
+	cfg, err := loadLayers(filename, lookupEnv)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.ValidateDatabase()
+}
+
+func loadLayers(filename string, lookupEnv func(string) (string, bool)) (Bot, error) {
+	cfg := Defaults()
+
+	if filename != "" {
+		if err := loadFile(filename, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	for _, env := range envVars {
+		if value, ok := lookupEnv(env.Name); ok {
+			if err := env.Set(&cfg, value); err != nil {
+				return cfg, errors.Wrapf(err, "reading %s", env.Name)
+			}
+		}
+	}
+
+	for _, env := range envVars {
+		path, ok := lookupEnv(env.Name + "_FILE")
+		if !ok || path == "" {
+			continue
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return cfg, errors.Wrapf(err, "reading %s_FILE", env.Name)
+		}
+		if err = env.Set(&cfg, strings.TrimSpace(string(contents))); err != nil {
+			return cfg, errors.Wrapf(err, "reading %s_FILE", env.Name)
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadFile(filename string, cfg *Bot) error {
+	contents, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		logrus.Infof("--> no config file at %s, using defaults and environment only", filename)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "reading config file %s", filename)
+	}
+
+	logrus.Info(fmt.Sprintf("--> reading config file: %s ...", filename))
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, cfg)
+	default:
+		err = json.Unmarshal(contents, cfg)
+	}
+
+	return errors.Wrapf(err, "parsing config file %s", filename)
+}
+
+// Validate reports every setting that would stop the bot from starting
+func (b Bot) Validate() error {
+	var problems []string
+	if b.Token == "" {
+		problems = append(problems, "botToken is required (set it in the config file, EGG_BOT_TOKEN or EGG_BOT_TOKEN_FILE)")
+	}
+	if b.RefreshIntervalMinutes < 0 {
+		problems = append(problems, "refreshIntervalMinutes can't be negative")
+	}
+	if b.RefreshConcurrency < 0 {
+		problems = append(problems, "refreshConcurrency can't be negative")
+	}
+	if b.SnapshotRetentionDays != 0 && b.SnapshotRetentionDays < minSnapshotRetentionDays {
+		problems = append(problems, fmt.Sprintf("snapshotRetentionDays must be 0 to keep snapshots forever, or at least %d to cover /gainers", minSnapshotRetentionDays))
+	}
+	if b.API.ClientVersion < 0 || b.API.TimeoutSeconds < 0 {
+		problems = append(problems, "api settings can't be negative")
+	}
+	for guildID, channelID := range b.LeaderboardChannels {
+		if guildID == "" || channelID == "" {
+			problems = append(problems, "leaderboardChannels needs a guild ID and a channel ID for every entry")
+			break
+		}
+	}
+	for guildID, channelID := range b.EventChannels {
+		if guildID == "" || channelID == "" {
+			problems = append(problems, "eventChannels needs a guild ID and a channel ID for every entry")
+			break
+		}
+	}
+	problems = append(problems, b.Database.problems()...)
+
+	return invalid(problems)
+}
+
+// ValidateDatabase reports every setting that would stop the datastore from connecting, ignoring the rest
+func (b Bot) ValidateDatabase() error {
+	return invalid(b.Database.problems())
+}
+
+func (d Database) problems() []string {
+	var problems []string
+	if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 || d.ConnMaxLifetimeMinutes < 0 {
+		problems = append(problems, "database pool settings can't be negative")
+	}
+	if d.URL == "sqlite-file" && d.SQLitePath == "" {
+		problems = append(problems, "database.sqlitePath is required for sqlite-file")
+	}
+	return problems
+}
+
+func invalid(problems []string) error {
+	if len(problems) > 0 {
+		return errors.New(fmt.Sprintf("invalid config: %s", strings.Join(problems, "; ")))
+	}
+	return nil
+}
+
+// Redacted is a copy of the configuration that is safe to log, with the bot token and any database password hidden
+func (b Bot) Redacted() Bot {
+	if b.Token != "" {
+		b.Token = redacted
+	}
+	b.Database.URL = redactDatabaseURL(b.Database.URL)
+	return b
+}
+
+// String renders the redacted configuration, so logging a Bot never leaks its secrets
+func (b Bot) String() string {
+	out, err := json.Marshal(b.Redacted())
+	if err != nil {
+		return fmt.Sprintf("unprintable config: %v", err)
+	}
+	return string(out)
+}
+
+var dsnPassword = regexp.MustCompile(`(password=)(\S+)`)
+
+func redactDatabaseURL(databaseURL string) string {
+	if u, err := url.Parse(databaseURL); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			return u.String()
+		}
+		return databaseURL
+	}
+	// key=value DSNs such as "host=db user=egg password=hunter2"
+	return dsnPassword.ReplaceAllString(databaseURL, "${1}"+redacted)
+}
+
+func parseBool(value string, out *bool) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.New(fmt.Sprintf("'%s' is not true or false", value))
+	}
+	*out = parsed
+	return nil
+}
+
+func parseInt(value string, out *int) error {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return errors.New(fmt.Sprintf("'%s' is not a whole number", value))
+	}
+	*out = parsed
+	return nil
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestLoad(t *testing.T) {
+	jsonFile := writeFile(t, "config.json", `{"botToken": "json-token", "guildID": "111", "refreshIntervalMinutes": 15}`)
+	yamlFile := writeFile(t, "config.yaml", "botToken: yaml-token\nguildID: \"222\"\ndatabase:\n  url: sqlite-in-memory\n")
+	secretFile := writeFile(t, "token", "secret-token\n")
+
+	tests := []struct {
+		name     string
+		filename string
+		env      map[string]string
+		check    func(t *testing.T, cfg Bot)
+		err      string
+	}{
+		{
+			name:     "json file over defaults",
+			filename: jsonFile,
+			check: func(t *testing.T, cfg Bot) {
+				require.Equal(t, "json-token", cfg.Token)
+				require.Equal(t, "111", cfg.GuildID)
+				require.Equal(t, 15, cfg.RefreshIntervalMinutes)
+				require.Equal(t, defaultRefreshConcurrency, cfg.RefreshConcurrency)
+				require.Zero(t, cfg.SnapshotRetention())
+				require.Equal(t, "sqlite-file", cfg.Database.URL)
+			},
+		},
+		{
+			name:     "yaml file",
+			filename: yamlFile,
+			check: func(t *testing.T, cfg Bot) {
+				require.Equal(t, "yaml-token", cfg.Token)
+				require.Equal(t, "222", cfg.GuildID)
+				require.Equal(t, "sqlite-in-memory", cfg.Database.URL)
+			},
+		},
+		{
+			name:     "environment over file",
+			filename: jsonFile,
+			env:      map[string]string{"EGG_GUILD_ID": "333", "EGG_REFRESH_CONCURRENCY": "8", "EGG_GLOBAL_COMMANDS": "true", "EGG_MEMBER_INTENT": "true"},
+			check: func(t *testing.T, cfg Bot) {
+				require.Equal(t, "json-token", cfg.Token)
+				require.Equal(t, "333", cfg.GuildID)
+				require.Equal(t, 8, cfg.RefreshConcurrency)
+				require.True(t, cfg.GlobalCommands)
+				require.True(t, cfg.MemberIntent)
+			},
+		},
+		{
+			name:     "secret file over environment",
+			filename: jsonFile,
+			env:      map[string]string{"EGG_BOT_TOKEN": "env-token", "EGG_BOT_TOKEN_FILE": secretFile},
+			check: func(t *testing.T, cfg Bot) {
+				require.Equal(t, "secret-token", cfg.Token)
+			},
+		},
+		{
+			name:     "missing file uses the environment alone",
+			filename: filepath.Join(t.TempDir(), "missing.json"),
+			env:      map[string]string{"EGG_BOT_TOKEN": "env-token"},
+			check: func(t *testing.T, cfg Bot) {
+				require.Equal(t, "env-token", cfg.Token)
+			},
+		},
+		{
+			name:     "missing token",
+			filename: filepath.Join(t.TempDir(), "missing.json"),
+			err:      "botToken is required",
+		},
+		{
+			name:     "event channel without a channel",
+			filename: writeFile(t, "events.json", `{"botToken": "json-token", "eventChannels": {"111": ""}}`),
+			err:      "eventChannels needs a guild ID and a channel ID",
+		},
+		{
+			name:     "malformed file",
+			filename: writeFile(t, "broken.json", `{"botToken": `),
+			err:      "parsing config file",
+		},
+		{
+			name:     "malformed environment variable",
+			filename: jsonFile,
+			env:      map[string]string{"EGG_REFRESH_INTERVAL_MINUTES": "soon"},
+			err:      "EGG_REFRESH_INTERVAL_MINUTES: 'soon' is not a whole number",
+		},
+		{
+			name:     "snapshot retention shorter than a month",
+			filename: jsonFile,
+			env:      map[string]string{"EGG_SNAPSHOT_RETENTION_DAYS": "7"},
+			err:      "snapshotRetentionDays must be 0 to keep snapshots forever, or at least 31",
+		},
+		{
+			name:     "missing secret file",
+			filename: jsonFile,
+			env:      map[string]string{"EGG_BOT_TOKEN_FILE": filepath.Join(t.TempDir(), "nope")},
+			err:      "reading EGG_BOT_TOKEN_FILE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(tt.filename, env(tt.env))
+			if tt.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadDatabase(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+
+	// the migrate command runs with database credentials alone
+	cfg, err := loadDatabase(missing, env(map[string]string{"EGG_DATABASE_URL": "postgres://egg:hunter2@db/egg"}))
+	require.NoError(t, err)
+	require.Empty(t, cfg.Token)
+	require.Equal(t, "postgres://egg:hunter2@db/egg", cfg.Database.URL)
+
+	_, err = loadDatabase(missing, env(map[string]string{"EGG_DATABASE_MAX_OPEN_CONNS": "-1"}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "database pool settings can't be negative")
+
+	_, err = load(missing, env(map[string]string{"EGG_DATABASE_URL": "postgres://egg:hunter2@db/egg"}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "botToken is required")
+}
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "sqlite-file", expected: "sqlite-file"},
+		{url: "postgres://egg:hunter2@db:5432/egg", expected: "postgres://egg:REDACTED@db:5432/egg"},
+		{url: "postgres://egg@db/egg", expected: "postgres://egg@db/egg"},
+		{url: "host=db user=egg password=hunter2 dbname=egg", expected: "host=db user=egg password=REDACTED dbname=egg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			cfg := Bot{Token: "token", Database: Database{URL: tt.url}}
+			require.Equal(t, tt.expected, cfg.Redacted().Database.URL)
+			require.Equal(t, "REDACTED", cfg.Redacted().Token)
+			require.False(t, strings.Contains(cfg.String(), "hunter2"))
+			require.False(t, strings.Contains(cfg.String(), `"token"`))
+		})
+	}
+}
@@ -3,6 +3,8 @@ package datastore
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm/clause"
@@ -30,14 +32,51 @@ type Transaction interface {
 
 	GetUsers() (Users, error)
 	GetUsersByDiscordName(discordName string) (Users, error)
+	GetUsersByDiscordID(discordID string) (Users, error)
+	GetUsersWithoutDiscordID() (Users, error)
 	GetUserByEggIncUserID(eggIncUserID string) (User, error)
 
 	DeleteUser(user User) error
+
+	AddUserToGuild(guildID, eggIncID string) error
+	RemoveUserFromGuild(guildID, eggIncID string) error
+	GetGuildUsers(guildID string) (Users, error)
+	GetUserGuildIDs(eggIncID string) ([]string, error)
+	GetUsersWithoutGuild() (Users, error)
+
+	CreateUserSnapshot(snapshot UserSnapshot) (UserSnapshot, error)
+	GetUserSnapshots(eggIncID string, since time.Time) (UserSnapshots, error)
+	GetGuildSnapshotRange(guildID string, since time.Time) (UserSnapshots, error)
+	DeleteSnapshotsBefore(before time.Time) error
+
+	CreateOrUpdateLeaderboard(leaderboard Leaderboard) (Leaderboard, error)
+	GetLeaderboards() (Leaderboards, error)
+	GetLeaderboardByGuildID(guildID string) (Leaderboard, error)
+
+	CreateOrUpdatePreference(preference Preference) (Preference, error)
+	GetPreference(discordID string) (Preference, error)
+
+	SaveMissions(missions Missions) error
+	GetDueMissions(now time.Time) (Missions, error)
+	MarkMissionsNotified(identifiers []string) error
+	DeleteMissionsBefore(before time.Time) error
+
+	CreateOrUpdateMissionAlert(alert MissionAlert) (MissionAlert, error)
+	GetMissionAlert(discordID string) (MissionAlert, error)
+	DeleteMissionAlert(discordID string) error
+
+	SaveAnnouncedEvents(events AnnouncedEvents) error
+	GetAnnouncedEvents(identifiers []string) (AnnouncedEvents, error)
+	DeleteAnnouncedEventsBefore(before time.Time) error
+
+	HasBackfilled(name string) (bool, error)
+	MarkBackfilled(name string) error
 }
 
 // User is the struct representation of a database table for storing user information
 type User struct {
 	EggIncID        string  `json:"egg_inc_id" gorm:"egg_inc_id;primarykey,unique;not null"`
+	DiscordID       string  `json:"discord_id" gorm:"discord_id;index"`
 	DiscordName     string  `json:"discord_name" gorm:"discord_name;not null"`
 	GameAccountName string  `json:"game_account_name" gorm:"game_account_name;unique"`
 	SoulFood        int32   `json:"soul_food" gorm:"soul_food"`
@@ -45,6 +84,15 @@ type User struct {
 	SoulEggs        float64 `json:"soul_eggs" gorm:"soul_eggs"`
 	ProphecyEggs    int32   `json:"prophecy_eggs" gorm:"prophecy_eggs"`
 
+	GoldenEggs          int64 `json:"golden_eggs" gorm:"golden_eggs"`
+	Prestiges           int32 `json:"prestiges" gorm:"prestiges"`
+	DroneTakedowns      int32 `json:"drone_takedowns" gorm:"drone_takedowns"`
+	EliteDroneTakedowns int32 `json:"elite_drone_takedowns" gorm:"elite_drone_takedowns"`
+	BoostsUsed          int32 `json:"boosts_used" gorm:"boosts_used"`
+	PiggyLevel          int32 `json:"piggy_level" gorm:"piggy_level"`
+	// TrophyLevels is the trophy level of each egg's farm, in egg order, separated by commas
+	TrophyLevels string `json:"trophy_levels" gorm:"trophy_levels"`
+
 	CreatedAt time.Time      `json:"created_at,omitempty"`
 	UpdatedAt time.Time      `json:"updated_at,omitempty"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
@@ -60,6 +108,147 @@ func (u Users) GetEggIncIDs() (ids []string) {
 	return
 }
 
+// Trophies is the trophy level of each egg's farm, in egg order
+func (u User) Trophies() []int32 {
+	if u.TrophyLevels == "" {
+		return nil
+	}
+
+	parts := strings.Split(u.TrophyLevels, ",")
+	levels := make([]int32, 0, len(parts))
+	for _, part := range parts {
+		level, err := strconv.Atoi(part)
+		if err != nil {
+			level = 0
+		}
+		levels = append(levels, int32(level))
+	}
+	return levels
+}
+
+// JoinTrophyLevels encodes the trophy level of each egg's farm for User.TrophyLevels
+func JoinTrophyLevels(levels []int32) string {
+	parts := make([]string, 0, len(levels))
+	for _, level := range levels {
+		parts = append(parts, strconv.Itoa(int(level)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// GuildUser is the struct representation of a database table for storing which guilds a user is registered in
+type GuildUser struct {
+	GuildID  string `json:"guild_id" gorm:"guild_id;primarykey;not null"`
+	EggIncID string `json:"egg_inc_id" gorm:"egg_inc_id;primarykey;not null"`
+
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// Snapshot captures a user's current progress as a UserSnapshot taken at the given time
+func (u User) Snapshot(takenAt time.Time) UserSnapshot {
+	return UserSnapshot{
+		EggIncID:      u.EggIncID,
+		SoulFood:      u.SoulFood,
+		ProphecyBonus: u.ProphecyBonus,
+		SoulEggs:      u.SoulEggs,
+		ProphecyEggs:  u.ProphecyEggs,
+		TakenAt:       takenAt,
+	}
+}
+
+// UserSnapshot is the struct representation of a database table for storing a user's progress at a point in time
+type UserSnapshot struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	EggIncID      string    `json:"egg_inc_id" gorm:"egg_inc_id;index;not null"`
+	SoulFood      int32     `json:"soul_food" gorm:"soul_food"`
+	ProphecyBonus int32     `json:"prophecy_bonus" gorm:"prophecy_bonus"`
+	SoulEggs      float64   `json:"soul_eggs" gorm:"soul_eggs"`
+	ProphecyEggs  int32     `json:"prophecy_eggs" gorm:"prophecy_eggs"`
+	TakenAt       time.Time `json:"taken_at" gorm:"taken_at;index;not null"`
+}
+
+// UserSnapshots is a slice of the UserSnapshot type
+type UserSnapshots []UserSnapshot
+
+// ByEggIncID groups snapshots by user, preserving their order
+func (s UserSnapshots) ByEggIncID() map[string]UserSnapshots {
+	grouped := make(map[string]UserSnapshots)
+	for _, snapshot := range s {
+		grouped[snapshot.EggIncID] = append(grouped[snapshot.EggIncID], snapshot)
+	}
+	return grouped
+}
+
+// Leaderboard is the struct representation of a database table for storing where a guild's leaderboard message lives
+type Leaderboard struct {
+	GuildID   string `json:"guild_id" gorm:"guild_id;primarykey;not null"`
+	ChannelID string `json:"channel_id" gorm:"channel_id;not null"`
+	MessageID string `json:"message_id" gorm:"message_id"`
+
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Leaderboards is a slice of the Leaderboard type
+type Leaderboards []Leaderboard
+
+// Preference is the struct representation of a database table for storing how a Discord user likes the bot to talk to them
+type Preference struct {
+	DiscordID       string `json:"discord_id" gorm:"discord_id;primarykey;not null"`
+	NumberNotation  string `json:"number_notation" gorm:"number_notation"`
+	NumberPrecision int    `json:"number_precision" gorm:"number_precision"`
+
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Mission is the struct representation of a database table for storing when each ship in flight lands
+type Mission struct {
+	Identifier string `json:"identifier" gorm:"identifier;primarykey;not null"`
+	EggIncID   string `json:"egg_inc_id" gorm:"egg_inc_id;index;not null"`
+	// Ship and DurationType are the game's enum values
+	Ship         int32     `json:"ship" gorm:"ship"`
+	DurationType int32     `json:"duration_type" gorm:"duration_type"`
+	ReturnsAt    time.Time `json:"returns_at" gorm:"returns_at;index;not null"`
+	Notified     bool      `json:"notified" gorm:"notified;not null;default:false"`
+
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Missions is a slice of the Mission type
+type Missions []Mission
+
+// MissionAlert is the struct representation of a database table for storing who wants to hear when their ships land
+type MissionAlert struct {
+	DiscordID string `json:"discord_id" gorm:"discord_id;primarykey;not null"`
+	// ChannelID is where to ping them, or empty to send a DM
+	ChannelID string `json:"channel_id" gorm:"channel_id"`
+
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// AnnouncedEvent is the struct representation of a database table for storing which events and sales have been
+// posted, so they're never posted twice
+type AnnouncedEvent struct {
+	Identifier string    `json:"identifier" gorm:"identifier;primarykey;not null"`
+	EndsAt     time.Time `json:"ends_at" gorm:"ends_at;index;not null"`
+
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// AnnouncedEvents is a slice of the AnnouncedEvent type
+type AnnouncedEvents []AnnouncedEvent
+
+// Backfill is the struct representation of a database table for storing which one-off data fixes have run,
+// so that fixes the bot applies at startup only ever apply once
+type Backfill struct {
+	Name string `json:"name" gorm:"name;primarykey;not null"`
+
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
 // Database implements the Datastore interface
 type Database struct {
 	DB *gorm.DB
@@ -150,7 +339,31 @@ func (t Txn) GetUsersByDiscordName(discordName string) (Users, error) {
 	}
 
 	if len(users) == 0 {
-		return Users{}, errors.New(fmt.Sprintf("no records found for provided Discord name: %s", discordName))
+		return Users{}, errors.Wrap(gorm.ErrRecordNotFound, fmt.Sprintf("no records found for provided Discord name: %s", discordName))
+	}
+
+	return users, nil
+}
+
+// GetUsersByDiscordID returns all users registered by a given Discord user ID
+func (t Txn) GetUsersByDiscordID(discordID string) (Users, error) {
+	var users Users
+	if err := t.Client.Where("discord_id = ?", discordID).Find(&users).Error; err != nil {
+		return Users{}, err
+	}
+
+	if len(users) == 0 {
+		return Users{}, errors.Wrap(gorm.ErrRecordNotFound, fmt.Sprintf("no records found for provided Discord ID: %s", discordID))
+	}
+
+	return users, nil
+}
+
+// GetUsersWithoutDiscordID returns users registered before Discord user IDs were recorded
+func (t Txn) GetUsersWithoutDiscordID() (Users, error) {
+	var users Users
+	if err := t.Client.Where("discord_id IS NULL OR discord_id = ''").Find(&users).Error; err != nil {
+		return Users{}, err
 	}
 
 	return users, nil
@@ -166,26 +379,271 @@ func (t Txn) GetUserByEggIncUserID(eggIncUserID string) (User, error) {
 	return user, nil
 }
 
-// DeleteUser removes a user from the datastore
+// DeleteUser removes a user and their guild registrations from the datastore
 func (t Txn) DeleteUser(user User) error {
+	if err := t.Client.Where("egg_inc_id = ?", user.EggIncID).Delete(&GuildUser{}).Error; err != nil {
+		return err
+	}
+	if err := t.Client.Where("egg_inc_id = ?", user.EggIncID).Delete(&Mission{}).Error; err != nil {
+		return err
+	}
+
 	return t.Client.Where("egg_inc_id = ?", user.EggIncID).Delete(&user).Error
 }
 
+// AddUserToGuild registers a user in a guild, doing nothing if they already are
+func (t Txn) AddUserToGuild(guildID, eggIncID string) error {
+	return t.Client.Clauses(clause.OnConflict{DoNothing: true}).Create(&GuildUser{
+		GuildID:  guildID,
+		EggIncID: eggIncID,
+	}).Error
+}
+
+// RemoveUserFromGuild removes a user's registration in a guild
+func (t Txn) RemoveUserFromGuild(guildID, eggIncID string) error {
+	return t.Client.Where("guild_id = ? AND egg_inc_id = ?", guildID, eggIncID).Delete(&GuildUser{}).Error
+}
+
+// GetGuildUsers returns all users registered in a given guild
+func (t Txn) GetGuildUsers(guildID string) (Users, error) {
+	var users Users
+	if err := t.Client.
+		Joins("JOIN guild_users ON guild_users.egg_inc_id = users.egg_inc_id").
+		Where("guild_users.guild_id = ?", guildID).
+		Find(&users).Error; err != nil {
+		return Users{}, err
+	}
+
+	return users, nil
+}
+
+// GetUserGuildIDs returns the IDs of every guild a user is registered in
+func (t Txn) GetUserGuildIDs(eggIncID string) ([]string, error) {
+	var guildIDs []string
+	if err := t.Client.Model(&GuildUser{}).Where("egg_inc_id = ?", eggIncID).Pluck("guild_id", &guildIDs).Error; err != nil {
+		return nil, err
+	}
+
+	return guildIDs, nil
+}
+
+// GetUsersWithoutGuild returns users that aren't registered in any guild, e.g. those from before guilds were tracked
+func (t Txn) GetUsersWithoutGuild() (Users, error) {
+	var users Users
+	if err := t.Client.Where("egg_inc_id NOT IN (?)", t.Client.Model(&GuildUser{}).Select("egg_inc_id")).Find(&users).Error; err != nil {
+		return Users{}, err
+	}
+
+	return users, nil
+}
+
+// CreateUserSnapshot records a user's progress at a point in time, defaulting to now
+func (t Txn) CreateUserSnapshot(snapshot UserSnapshot) (UserSnapshot, error) {
+	if snapshot.TakenAt.IsZero() {
+		snapshot.TakenAt = time.Now()
+	}
+
+	if err := t.Client.Create(&snapshot).Error; err != nil {
+		return UserSnapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// GetUserSnapshots returns a user's snapshots taken at or after since, oldest first
+func (t Txn) GetUserSnapshots(eggIncID string, since time.Time) (UserSnapshots, error) {
+	var snapshots UserSnapshots
+	if err := t.Client.Where("egg_inc_id = ? AND taken_at >= ?", eggIncID, since).Order("taken_at").Find(&snapshots).Error; err != nil {
+		return UserSnapshots{}, err
+	}
+
+	return snapshots, nil
+}
+
+// GetGuildSnapshotRange returns the first and last snapshot taken at or after since of every user registered in a
+// guild, oldest first. Users with a single snapshot in that window get just the one.
+func (t Txn) GetGuildSnapshotRange(guildID string, since time.Time) (UserSnapshots, error) {
+	var snapshots UserSnapshots
+	if err := t.Client.Raw(`SELECT s.* FROM user_snapshots s
+		JOIN (
+			SELECT r.egg_inc_id, MIN(r.taken_at) AS first_at, MAX(r.taken_at) AS last_at
+			FROM user_snapshots r
+			JOIN guild_users g ON g.egg_inc_id = r.egg_inc_id AND g.guild_id = ?
+			WHERE r.taken_at >= ?
+			GROUP BY r.egg_inc_id
+		) w ON w.egg_inc_id = s.egg_inc_id AND (s.taken_at = w.first_at OR s.taken_at = w.last_at)
+		ORDER BY s.taken_at, s.id`, guildID, since).Scan(&snapshots).Error; err != nil {
+		return UserSnapshots{}, err
+	}
+
+	return snapshots, nil
+}
+
+// DeleteSnapshotsBefore removes snapshots taken before a given time
+func (t Txn) DeleteSnapshotsBefore(before time.Time) error {
+	return t.Client.Where("taken_at < ?", before).Delete(&UserSnapshot{}).Error
+}
+
+// CreateOrUpdateLeaderboard adds or updates a guild's leaderboard location in the datastore
+func (t Txn) CreateOrUpdateLeaderboard(leaderboard Leaderboard) (Leaderboard, error) {
+	if err := t.Client.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "guild_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channel_id", "message_id", "updated_at"}),
+	}).Create(&leaderboard).Error; err != nil {
+		return leaderboard, err
+	}
+
+	return t.GetLeaderboardByGuildID(leaderboard.GuildID)
+}
+
+// GetLeaderboards returns the leaderboard location of every guild that has one
+func (t Txn) GetLeaderboards() (Leaderboards, error) {
+	var leaderboards Leaderboards
+	if err := t.Client.Find(&leaderboards).Error; err != nil {
+		return Leaderboards{}, err
+	}
+
+	return leaderboards, nil
+}
+
+// GetLeaderboardByGuildID returns the leaderboard location for a given guild
+func (t Txn) GetLeaderboardByGuildID(guildID string) (Leaderboard, error) {
+	var leaderboard Leaderboard
+	if err := t.Client.Where("guild_id = ?", guildID).First(&leaderboard).Error; err != nil {
+		return Leaderboard{}, err
+	}
+
+	return leaderboard, nil
+}
+
+// CreateOrUpdatePreference adds or updates a Discord user's preferences in the datastore
+func (t Txn) CreateOrUpdatePreference(preference Preference) (Preference, error) {
+	if err := t.Client.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "discord_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"number_notation", "number_precision", "updated_at"}),
+	}).Create(&preference).Error; err != nil {
+		return preference, err
+	}
+
+	return t.GetPreference(preference.DiscordID)
+}
+
+// GetPreference returns a Discord user's preferences
+func (t Txn) GetPreference(discordID string) (Preference, error) {
+	var preference Preference
+	if err := t.Client.Where("discord_id = ?", discordID).First(&preference).Error; err != nil {
+		return Preference{}, err
+	}
+
+	return preference, nil
+}
+
+// SaveMissions adds or updates missions in the datastore. Whether a landing has been announced is only set when a
+// mission is first added, so saving it again never announces it twice.
+func (t Txn) SaveMissions(missions Missions) error {
+	if len(missions) == 0 {
+		return nil
+	}
+
+	return t.Client.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "identifier"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ship", "duration_type", "returns_at", "updated_at"}),
+	}).Create(&missions).Error
+}
+
+// GetDueMissions returns the missions that have landed by now but haven't been announced
+func (t Txn) GetDueMissions(now time.Time) (Missions, error) {
+	var missions Missions
+	if err := t.Client.Where("returns_at <= ? AND notified = ?", now, false).Order("returns_at").Find(&missions).Error; err != nil {
+		return Missions{}, err
+	}
+
+	return missions, nil
+}
+
+// MarkMissionsNotified records that missions' landings have been announced
+func (t Txn) MarkMissionsNotified(identifiers []string) error {
+	if len(identifiers) == 0 {
+		return nil
+	}
+
+	return t.Client.Model(&Mission{}).Where("identifier IN ?", identifiers).Update("notified", true).Error
+}
+
+// DeleteMissionsBefore removes missions that landed before a given time
+func (t Txn) DeleteMissionsBefore(before time.Time) error {
+	return t.Client.Where("returns_at < ?", before).Delete(&Mission{}).Error
+}
+
+// CreateOrUpdateMissionAlert adds or updates where a Discord user wants to hear about their ships landing
+func (t Txn) CreateOrUpdateMissionAlert(alert MissionAlert) (MissionAlert, error) {
+	if err := t.Client.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "discord_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channel_id", "updated_at"}),
+	}).Create(&alert).Error; err != nil {
+		return alert, err
+	}
+
+	return t.GetMissionAlert(alert.DiscordID)
+}
+
+// GetMissionAlert returns where a Discord user wants to hear about their ships landing
+func (t Txn) GetMissionAlert(discordID string) (MissionAlert, error) {
+	var alert MissionAlert
+	if err := t.Client.Where("discord_id = ?", discordID).First(&alert).Error; err != nil {
+		return MissionAlert{}, err
+	}
+
+	return alert, nil
+}
+
+// DeleteMissionAlert stops telling a Discord user about their ships landing
+func (t Txn) DeleteMissionAlert(discordID string) error {
+	return t.Client.Where("discord_id = ?", discordID).Delete(&MissionAlert{}).Error
+}
+
+// Options is the values required to connect to a database
+type Options struct {
+	// URL is a Postgres URL or DSN, or one of "sqlite-file" and "sqlite-in-memory". Defaults to "sqlite-file".
+	URL string
+	// SQLitePath is where "sqlite-file" keeps its database. Defaults to "./db.sqlite".
+	SQLitePath string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	SilenceTransactionLogs bool
+}
+
 // ConnectDatabase stolen from tinkerbell-cerberus and Wyatt ;-) for connecting to different DB types
 func ConnectDatabase(url string, silenceTransactionLogs bool) (*gorm.DB, error) {
+	return Connect(Options{
+		URL:                    url,
+		SilenceTransactionLogs: silenceTransactionLogs,
+	})
+}
+
+// Connect opens a database described by opts and applies its connection pool settings
+func Connect(opts Options) (*gorm.DB, error) {
+	sqlitePath := opts.SQLitePath
+	if sqlitePath == "" {
+		sqlitePath = "./db.sqlite"
+	}
+
 	var backendPath gorm.Dialector
-	switch url {
+	switch opts.URL {
 	// https://www.sqlite.org/inmemorydb.html
 	case "sqlite-in-memory":
 		backendPath = sqlite.Open("file::memory:")
-	case "sqlite-file":
-		backendPath = sqlite.Open("./db.sqlite")
+	case "sqlite-file", "":
+		backendPath = sqlite.Open(sqlitePath)
 	default:
-		backendPath = postgres.Open(url)
+		backendPath = postgres.Open(opts.URL)
 	}
 
 	var config gorm.Config
-	switch silenceTransactionLogs {
+	switch opts.SilenceTransactionLogs {
 	case true:
 		config = gorm.Config{
 			Logger: logger.Default.LogMode(logger.Silent),
@@ -199,5 +657,64 @@ func ConnectDatabase(url string, silenceTransactionLogs bool) (*gorm.DB, error)
 		return nil, err
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+
 	return db, nil
 }
+
+// SaveAnnouncedEvents records that events or sales have been posted
+func (t Txn) SaveAnnouncedEvents(events AnnouncedEvents) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	return t.Client.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "identifier"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ends_at", "updated_at"}),
+	}).Create(&events).Error
+}
+
+// GetAnnouncedEvents returns which of the given events and sales have already been posted
+func (t Txn) GetAnnouncedEvents(identifiers []string) (AnnouncedEvents, error) {
+	var events AnnouncedEvents
+	if len(identifiers) == 0 {
+		return events, nil
+	}
+	if err := t.Client.Where("identifier IN ?", identifiers).Find(&events).Error; err != nil {
+		return AnnouncedEvents{}, err
+	}
+
+	return events, nil
+}
+
+// DeleteAnnouncedEventsBefore forgets events and sales that ended before a given time
+func (t Txn) DeleteAnnouncedEventsBefore(before time.Time) error {
+	return t.Client.Where("ends_at < ?", before).Delete(&AnnouncedEvent{}).Error
+}
+
+// HasBackfilled reports whether a one-off data fix has already run
+func (t Txn) HasBackfilled(name string) (bool, error) {
+	var count int64
+	if err := t.Client.Model(&Backfill{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// MarkBackfilled records that a one-off data fix has run
+func (t Txn) MarkBackfilled(name string) error {
+	return t.Client.Clauses(clause.OnConflict{DoNothing: true}).Create(&Backfill{Name: name}).Error
+}
@@ -2,7 +2,9 @@ package datastore
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -12,6 +14,7 @@ func TestDatastoreInterfaces(t *testing.T) {
 	eggIncID := uuid.New().String()
 	testUser := User{
 		EggIncID:    eggIncID,
+		DiscordID:   "1234567890",
 		DiscordName: "krohmag",
 	}
 
@@ -29,7 +32,7 @@ func TestDatastoreInterfaces(t *testing.T) {
 
 	datastore := Database{DB: db}
 
-	require.NoError(t, datastore.DB.AutoMigrate(User{}))
+	require.NoError(t, Migrate(datastore.DB))
 	require.True(t, datastore.Ping())
 
 	ctx := context.Background()
@@ -54,6 +57,14 @@ func TestDatastoreInterfaces(t *testing.T) {
 		require.NoError(t, txErr)
 		require.Equal(t, testUserUpdate.SoulFood, usersByDiscordName[0].SoulFood)
 
+		usersByDiscordID, txErr := tx.GetUsersByDiscordID(testUser.DiscordID)
+		require.NoError(t, txErr)
+		require.Equal(t, testUserUpdate.SoulEggs, usersByDiscordID[0].SoulEggs)
+
+		missingDiscordID, txErr := tx.GetUsersWithoutDiscordID()
+		require.NoError(t, txErr)
+		require.Empty(t, missingDiscordID)
+
 		users, txErr := tx.GetUsers()
 		require.NoError(t, txErr)
 		require.Equal(t, []string{eggIncID}, users.GetEggIncIDs())
@@ -71,6 +82,319 @@ func TestDatastoreInterfaces(t *testing.T) {
 	})
 }
 
+func TestConnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "egg.sqlite")
+
+	db, err := Connect(Options{
+		URL:                    "sqlite-file",
+		SQLitePath:             path,
+		MaxOpenConns:           3,
+		MaxIdleConns:           2,
+		ConnMaxLifetime:        time.Minute,
+		SilenceTransactionLogs: true,
+	})
+	require.NoError(t, err)
+	require.True(t, Database{DB: db}.Ping())
+	require.NoError(t, Migrate(db))
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+	require.FileExists(t, path)
+	require.NoError(t, sqlDB.Close())
+}
+
+func TestGuildUsers(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	datastore := Database{DB: db}
+	require.NoError(t, Migrate(datastore.DB))
+
+	tx, err := datastore.Transaction(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, user := range []User{
+		{EggIncID: "EI1111", DiscordName: "krohmag", GameAccountName: "akroh"},
+		{EggIncID: "EI2222", DiscordName: "someone", GameAccountName: "someone"},
+		{EggIncID: "EI3333", DiscordName: "legacy", GameAccountName: "legacy"},
+	} {
+		_, err = tx.CreateOrUpdateUser(user)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tx.AddUserToGuild("guild-a", "EI1111"))
+	require.NoError(t, tx.AddUserToGuild("guild-a", "EI1111"))
+	require.NoError(t, tx.AddUserToGuild("guild-b", "EI1111"))
+	require.NoError(t, tx.AddUserToGuild("guild-b", "EI2222"))
+
+	guildA, err := tx.GetGuildUsers("guild-a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"EI1111"}, guildA.GetEggIncIDs())
+
+	guildB, err := tx.GetGuildUsers("guild-b")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"EI1111", "EI2222"}, guildB.GetEggIncIDs())
+
+	guildIDs, err := tx.GetUserGuildIDs("EI1111")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"guild-a", "guild-b"}, guildIDs)
+
+	orphans, err := tx.GetUsersWithoutGuild()
+	require.NoError(t, err)
+	require.Equal(t, []string{"EI3333"}, orphans.GetEggIncIDs())
+
+	require.NoError(t, tx.RemoveUserFromGuild("guild-b", "EI1111"))
+	guildB, err = tx.GetGuildUsers("guild-b")
+	require.NoError(t, err)
+	require.Equal(t, []string{"EI2222"}, guildB.GetEggIncIDs())
+
+	require.NoError(t, tx.DeleteUser(User{EggIncID: "EI2222"}))
+	guildB, err = tx.GetGuildUsers("guild-b")
+	require.NoError(t, err)
+	require.Empty(t, guildB)
+}
+
+func TestLeaderboards(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	datastore := Database{DB: db}
+	require.NoError(t, Migrate(datastore.DB))
+
+	tx, err := datastore.Transaction(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.GetLeaderboardByGuildID("guild")
+	require.Error(t, err)
+
+	created, err := tx.CreateOrUpdateLeaderboard(Leaderboard{GuildID: "guild", ChannelID: "channel", MessageID: "message"})
+	require.NoError(t, err)
+	require.Equal(t, "message", created.MessageID)
+
+	moved, err := tx.CreateOrUpdateLeaderboard(Leaderboard{GuildID: "guild", ChannelID: "other-channel", MessageID: "other-message"})
+	require.NoError(t, err)
+	require.Equal(t, "other-channel", moved.ChannelID)
+	require.Equal(t, "other-message", moved.MessageID)
+
+	leaderboards, err := tx.GetLeaderboards()
+	require.NoError(t, err)
+	require.Len(t, leaderboards, 1)
+}
+
+func TestPreferences(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	datastore := Database{DB: db}
+	require.NoError(t, Migrate(datastore.DB))
+
+	tx, err := datastore.Transaction(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.GetPreference("1234567890")
+	require.Error(t, err)
+
+	created, err := tx.CreateOrUpdatePreference(Preference{DiscordID: "1234567890", NumberNotation: "scientific", NumberPrecision: 2})
+	require.NoError(t, err)
+	require.Equal(t, "scientific", created.NumberNotation)
+
+	updated, err := tx.CreateOrUpdatePreference(Preference{DiscordID: "1234567890", NumberNotation: "suffix", NumberPrecision: 0})
+	require.NoError(t, err)
+	require.Equal(t, "suffix", updated.NumberNotation)
+	require.Zero(t, updated.NumberPrecision)
+}
+
+func TestMissions(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	datastore := Database{DB: db}
+	require.NoError(t, Migrate(datastore.DB))
+
+	tx, err := datastore.Transaction(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	now := time.Now()
+	require.NoError(t, tx.SaveMissions(Missions{
+		{Identifier: "landed", EggIncID: "EI1111", ReturnsAt: now.Add(-time.Minute)},
+		{Identifier: "flying", EggIncID: "EI1111", ReturnsAt: now.Add(time.Hour)},
+	}))
+
+	due, err := tx.GetDueMissions(now)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, "landed", due[0].Identifier)
+
+	require.NoError(t, tx.MarkMissionsNotified([]string{"landed"}))
+	// saving a mission again, e.g. on the next refresh, doesn't announce it twice
+	require.NoError(t, tx.SaveMissions(Missions{{Identifier: "landed", EggIncID: "EI1111", ReturnsAt: now.Add(-time.Minute)}}))
+	due, err = tx.GetDueMissions(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, "flying", due[0].Identifier)
+
+	require.NoError(t, tx.DeleteMissionsBefore(now))
+	due, err = tx.GetDueMissions(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	// missions that had landed before they were first saved are never due
+	require.NoError(t, tx.SaveMissions(Missions{{Identifier: "old", EggIncID: "EI1111", ReturnsAt: now.Add(-time.Minute), Notified: true}}))
+	due, err = tx.GetDueMissions(now)
+	require.NoError(t, err)
+	require.Empty(t, due)
+
+	_, err = tx.GetMissionAlert("1234567890")
+	require.Error(t, err)
+
+	alert, err := tx.CreateOrUpdateMissionAlert(MissionAlert{DiscordID: "1234567890", ChannelID: "channel"})
+	require.NoError(t, err)
+	require.Equal(t, "channel", alert.ChannelID)
+
+	alert, err = tx.CreateOrUpdateMissionAlert(MissionAlert{DiscordID: "1234567890"})
+	require.NoError(t, err)
+	require.Empty(t, alert.ChannelID)
+
+	require.NoError(t, tx.DeleteMissionAlert("1234567890"))
+	_, err = tx.GetMissionAlert("1234567890")
+	require.Error(t, err)
+}
+
+func TestAnnouncedEvents(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	datastore := Database{DB: db}
+	require.NoError(t, Migrate(datastore.DB))
+
+	tx, err := datastore.Transaction(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	now := time.Now()
+	announced, err := tx.GetAnnouncedEvents([]string{"event:e1"})
+	require.NoError(t, err)
+	require.Empty(t, announced)
+
+	require.NoError(t, tx.SaveAnnouncedEvents(AnnouncedEvents{
+		{Identifier: "event:e1", EndsAt: now.Add(time.Hour)},
+		{Identifier: "sale:piggy", EndsAt: now.Add(-time.Hour)},
+	}))
+	// posting the same event again only moves when it ends
+	require.NoError(t, tx.SaveAnnouncedEvents(AnnouncedEvents{{Identifier: "event:e1", EndsAt: now.Add(2 * time.Hour)}}))
+
+	announced, err = tx.GetAnnouncedEvents([]string{"event:e1", "sale:piggy", "event:e2"})
+	require.NoError(t, err)
+	require.Len(t, announced, 2)
+
+	require.NoError(t, tx.DeleteAnnouncedEventsBefore(now))
+	announced, err = tx.GetAnnouncedEvents([]string{"event:e1", "sale:piggy"})
+	require.NoError(t, err)
+	require.Len(t, announced, 1)
+	require.Equal(t, "event:e1", announced[0].Identifier)
+}
+
+func TestBackfills(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	datastore := Database{DB: db}
+	require.NoError(t, Migrate(datastore.DB))
+
+	tx, err := datastore.Transaction(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	done, err := tx.HasBackfilled("guild users")
+	require.NoError(t, err)
+	require.False(t, done)
+
+	require.NoError(t, tx.MarkBackfilled("guild users"))
+	require.NoError(t, tx.MarkBackfilled("guild users"))
+
+	done, err = tx.HasBackfilled("guild users")
+	require.NoError(t, err)
+	require.True(t, done)
+
+	done, err = tx.HasBackfilled("discord ids")
+	require.NoError(t, err)
+	require.False(t, done)
+}
+
+func TestUserSnapshots(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	datastore := Database{DB: db}
+	require.NoError(t, Migrate(datastore.DB))
+
+	tx, err := datastore.Transaction(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	now := time.Now()
+	for _, snapshot := range []UserSnapshot{
+		{EggIncID: "EI1111", SoulEggs: 1, TakenAt: now.Add(-48 * time.Hour)},
+		{EggIncID: "EI1111", SoulEggs: 2, TakenAt: now.Add(-12 * time.Hour)},
+		{EggIncID: "EI2222", SoulEggs: 5, TakenAt: now.Add(-6 * time.Hour)},
+		{EggIncID: "EI1111", SoulEggs: 3},
+	} {
+		_, err = tx.CreateUserSnapshot(snapshot)
+		require.NoError(t, err)
+	}
+
+	daily, err := tx.GetUserSnapshots("EI1111", now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, daily, 2)
+	require.Equal(t, float64(2), daily[0].SoulEggs)
+	require.Equal(t, float64(3), daily[1].SoulEggs)
+
+	require.NoError(t, tx.AddUserToGuild("guild-a", "EI1111"))
+	require.NoError(t, tx.AddUserToGuild("guild-a", "EI2222"))
+	require.NoError(t, tx.AddUserToGuild("guild-b", "EI3333"))
+	for _, snapshot := range []UserSnapshot{
+		{EggIncID: "EI1111", SoulEggs: 2.5, TakenAt: now.Add(-time.Hour)},
+		{EggIncID: "EI3333", SoulEggs: 9, TakenAt: now.Add(-time.Hour)},
+	} {
+		_, err = tx.CreateUserSnapshot(snapshot)
+		require.NoError(t, err)
+	}
+
+	// only the first and last snapshot of each of the guild's users in the window
+	window, err := tx.GetGuildSnapshotRange("guild-a", now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	grouped := window.ByEggIncID()
+	require.Len(t, grouped, 2)
+	require.Len(t, grouped["EI1111"], 2)
+	require.Equal(t, float64(2), grouped["EI1111"][0].SoulEggs)
+	require.Equal(t, float64(3), grouped["EI1111"][1].SoulEggs)
+	require.Len(t, grouped["EI2222"], 1)
+
+	require.NoError(t, tx.DeleteSnapshotsBefore(now.Add(-24*time.Hour)))
+	history, err := tx.GetUserSnapshots("EI1111", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+}
+
 func runTransaction(t *testing.T, ctx context.Context, datastore Database, testUser, testUserUpdate User, commit bool) error {
 	tx, err := datastore.Transaction(ctx)
 	if err != nil {
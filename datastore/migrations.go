@@ -0,0 +1,376 @@
+package datastore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Migration is a single versioned change to the database schema or its data.
+// Migrations describe tables with their own frozen structs rather than the live models,
+// so that later changes to a model can't change what an old migration does.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is the struct representation of a database table for recording which migrations have been applied
+type SchemaMigration struct {
+	Version   int       `json:"version" gorm:"version;primarykey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"name;not null"`
+	AppliedAt time.Time `json:"applied_at" gorm:"applied_at;not null"`
+}
+
+// MigrationState is a known migration along with when it was applied, if it has been
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// String renders the state as a line for the migrate status command
+func (m MigrationState) String() string {
+	if !m.Applied {
+		return fmt.Sprintf("%04d %-32s pending", m.Version, m.Name)
+	}
+	return fmt.Sprintf("%04d %-32s applied %s", m.Version, m.Name, m.AppliedAt.Format(time.RFC3339))
+}
+
+// Migrate applies every migration that hasn't been applied yet, oldest first, each in its own transaction
+func Migrate(db *gorm.DB) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err = db.Transaction(func(tx *gorm.DB) error {
+			if upErr := migration.Up(tx); upErr != nil {
+				return upErr
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		}); err != nil {
+			return errors.Wrapf(err, "applying migration %04d %s", migration.Version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+// MigrationStatus reports every known migration and whether it has been applied
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		record, ok := applied[migration.Version]
+		states = append(states, MigrationState{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: record.AppliedAt,
+		})
+	}
+
+	return states, nil
+}
+
+// RollbackLast reverts the most recently applied migration
+func RollbackLast(db *gorm.DB) (Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		if err = db.Transaction(func(tx *gorm.DB) error {
+			if downErr := migration.Down(tx); downErr != nil {
+				return downErr
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		}); err != nil {
+			return Migration{}, errors.Wrapf(err, "rolling back migration %04d %s", migration.Version, migration.Name)
+		}
+
+		return migration, nil
+	}
+
+	return Migration{}, errors.New("there are no applied migrations to roll back")
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]SchemaMigration)
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+// migrations is every schema change in the order it must be applied. Never edit or reorder an entry
+// once it has shipped; add a new one instead.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create users",
+		Up: func(tx *gorm.DB) error {
+			// databases created before migrations existed already have this table, which AutoMigrate leaves alone
+			return tx.AutoMigrate(&userV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&userV1{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create leaderboards",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&leaderboardV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&leaderboardV2{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create user snapshots",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&userSnapshotV3{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&userSnapshotV3{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add users discord id",
+		// existing rows are matched to a Discord user ID by the bot at startup, since that needs the Discord API
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&userV4{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasIndex(&userV4{}, "DiscordID") {
+				if err := tx.Migrator().DropIndex(&userV4{}, "DiscordID"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropColumn(&userV4{}, "DiscordID")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create guild users",
+		// existing users are assigned to the configured guild by the bot at startup, once, which backfills records
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&guildUserV5{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&guildUserV5{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add users stats",
+		// filled in for existing users by their next refresh
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&userV6{})
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range []string{"GoldenEggs", "Prestiges", "DroneTakedowns", "EliteDroneTakedowns", "BoostsUsed", "PiggyLevel", "TrophyLevels"} {
+				if err := tx.Migrator().DropColumn(&userV6{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create preferences",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&preferenceV7{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&preferenceV7{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create missions",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&missionV8{}, &missionAlertV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&missionV8{}, &missionAlertV8{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create announced events",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&announcedEventV9{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&announcedEventV9{})
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create backfills",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&backfillV10{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&backfillV10{})
+		},
+	},
+}
+
+type userV1 struct {
+	EggIncID        string  `gorm:"egg_inc_id;primarykey,unique;not null"`
+	DiscordName     string  `gorm:"discord_name;not null"`
+	GameAccountName string  `gorm:"game_account_name;unique"`
+	SoulFood        int32   `gorm:"soul_food"`
+	ProphecyBonus   int32   `gorm:"prophecy_bonus"`
+	SoulEggs        float64 `gorm:"soul_eggs"`
+	ProphecyEggs    int32   `gorm:"prophecy_eggs"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (userV1) TableName() string { return "users" }
+
+type leaderboardV2 struct {
+	GuildID   string `gorm:"guild_id;primarykey;not null"`
+	ChannelID string `gorm:"channel_id;not null"`
+	MessageID string `gorm:"message_id"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (leaderboardV2) TableName() string { return "leaderboards" }
+
+type userSnapshotV3 struct {
+	ID            uint      `gorm:"primarykey"`
+	EggIncID      string    `gorm:"egg_inc_id;index;not null"`
+	SoulFood      int32     `gorm:"soul_food"`
+	ProphecyBonus int32     `gorm:"prophecy_bonus"`
+	SoulEggs      float64   `gorm:"soul_eggs"`
+	ProphecyEggs  int32     `gorm:"prophecy_eggs"`
+	TakenAt       time.Time `gorm:"taken_at;index;not null"`
+}
+
+func (userSnapshotV3) TableName() string { return "user_snapshots" }
+
+type userV4 struct {
+	userV1
+	DiscordID string `gorm:"discord_id;index"`
+}
+
+func (userV4) TableName() string { return "users" }
+
+type guildUserV5 struct {
+	GuildID  string `gorm:"guild_id;primarykey;not null"`
+	EggIncID string `gorm:"egg_inc_id;primarykey;not null"`
+
+	CreatedAt time.Time
+}
+
+func (guildUserV5) TableName() string { return "guild_users" }
+
+type userV6 struct {
+	userV4
+	GoldenEggs          int64  `gorm:"golden_eggs"`
+	Prestiges           int32  `gorm:"prestiges"`
+	DroneTakedowns      int32  `gorm:"drone_takedowns"`
+	EliteDroneTakedowns int32  `gorm:"elite_drone_takedowns"`
+	BoostsUsed          int32  `gorm:"boosts_used"`
+	PiggyLevel          int32  `gorm:"piggy_level"`
+	TrophyLevels        string `gorm:"trophy_levels"`
+}
+
+func (userV6) TableName() string { return "users" }
+
+type preferenceV7 struct {
+	DiscordID       string `gorm:"discord_id;primarykey;not null"`
+	NumberNotation  string `gorm:"number_notation"`
+	NumberPrecision int    `gorm:"number_precision"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (preferenceV7) TableName() string { return "preferences" }
+
+type missionV8 struct {
+	Identifier   string    `gorm:"identifier;primarykey;not null"`
+	EggIncID     string    `gorm:"egg_inc_id;index;not null"`
+	Ship         int32     `gorm:"ship"`
+	DurationType int32     `gorm:"duration_type"`
+	ReturnsAt    time.Time `gorm:"returns_at;index;not null"`
+	Notified     bool      `gorm:"notified;not null;default:false"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (missionV8) TableName() string { return "missions" }
+
+type missionAlertV8 struct {
+	DiscordID string `gorm:"discord_id;primarykey;not null"`
+	ChannelID string `gorm:"channel_id"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (missionAlertV8) TableName() string { return "mission_alerts" }
+
+type announcedEventV9 struct {
+	Identifier string    `gorm:"identifier;primarykey;not null"`
+	EndsAt     time.Time `gorm:"ends_at;index;not null"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (announcedEventV9) TableName() string { return "announced_events" }
+
+type backfillV10 struct {
+	Name string `gorm:"name;primarykey;not null"`
+
+	CreatedAt time.Time
+}
+
+func (backfillV10) TableName() string { return "backfills" }
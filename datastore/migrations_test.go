@@ -0,0 +1,76 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	t.Run("fresh database", func(t *testing.T) {
+		require.NoError(t, Migrate(db))
+		require.NoError(t, Migrate(db))
+
+		states, err := MigrationStatus(db)
+		require.NoError(t, err)
+		require.Len(t, states, len(migrations))
+		for _, state := range states {
+			require.True(t, state.Applied, state.String())
+		}
+
+		require.True(t, db.Migrator().HasColumn(&User{}, "DiscordID"))
+		require.True(t, db.Migrator().HasTable(&GuildUser{}))
+		require.True(t, db.Migrator().HasColumn(&User{}, "TrophyLevels"))
+		require.True(t, db.Migrator().HasTable(&Preference{}))
+		require.True(t, db.Migrator().HasTable(&Mission{}))
+		require.True(t, db.Migrator().HasTable(&MissionAlert{}))
+		require.True(t, db.Migrator().HasTable(&AnnouncedEvent{}))
+		require.True(t, db.Migrator().HasTable(&Backfill{}))
+	})
+
+	t.Run("rollback the last migration", func(t *testing.T) {
+		rolledBack, err := RollbackLast(db)
+		require.NoError(t, err)
+		require.Equal(t, migrations[len(migrations)-1].Version, rolledBack.Version)
+
+		states, err := MigrationStatus(db)
+		require.NoError(t, err)
+		require.False(t, states[len(states)-1].Applied)
+		require.True(t, states[len(states)-2].Applied)
+
+		require.NoError(t, Migrate(db))
+		states, err = MigrationStatus(db)
+		require.NoError(t, err)
+		require.True(t, states[len(states)-1].Applied)
+	})
+
+	t.Run("rollback everything", func(t *testing.T) {
+		for range migrations {
+			_, err := RollbackLast(db)
+			require.NoError(t, err)
+		}
+
+		_, err := RollbackLast(db)
+		require.Error(t, err)
+		require.False(t, db.Migrator().HasTable(&User{}))
+	})
+}
+
+func TestMigrationsAdoptExistingDatabase(t *testing.T) {
+	db, err := ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+
+	// databases from before migrations existed were built with AutoMigrate and already hold users
+	require.NoError(t, db.AutoMigrate(&userV1{}))
+	require.NoError(t, db.Create(&userV1{EggIncID: "EI1111", DiscordName: "krohmag", GameAccountName: "akroh"}).Error)
+
+	require.NoError(t, Migrate(db))
+
+	var user User
+	require.NoError(t, db.Where("egg_inc_id = ?", "EI1111").First(&user).Error)
+	require.Equal(t, "krohmag", user.DiscordName)
+	require.Empty(t, user.DiscordID)
+}
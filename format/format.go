@@ -0,0 +1,169 @@
+// Package format writes the enormous numbers Egg, Inc. deals in the way players are used to reading them
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Notation is a way of writing a number
+type Notation string
+
+const (
+	// Suffix uses the game's suffixes, e.g. 1.500Q
+	Suffix Notation = "suffix"
+	// Scientific uses scientific notation, e.g. 1.500e18
+	Scientific Notation = "scientific"
+	// Magnitude only gives the order of magnitude, e.g. 10^18.176
+	Magnitude Notation = "magnitude"
+)
+
+// Notations is every notation in the order they're offered
+var Notations = []Notation{Suffix, Scientific, Magnitude}
+
+// Valid reports whether n is a known notation
+func (n Notation) Valid() bool {
+	for _, notation := range Notations {
+		if n == notation {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// DefaultPrecision is how many decimal places are shown when no preference is set
+	DefaultPrecision = 3
+	// MaxPrecision is the most decimal places that can be asked for
+	MaxPrecision = 6
+)
+
+// Suffixes are what the game writes after a number for each power of a thousand, starting at one.
+// The game itself capitalises thousands, millions and billions but players here never have.
+var Suffixes = []string{
+	"", "k", "m", "b", "T", "q", "Q", "s", "S", "o", "N",
+	"d", "U", "D", "Td", "qd", "Qd", "sd", "Sd", "Od", "Nd",
+	"V", "uV", "dV", "tV", "qV", "QV", "sV", "SV", "OV", "NV",
+	"tT",
+}
+
+// Options is how numbers are written. The zero value writes them like Default.
+type Options struct {
+	Notation  Notation `json:"notation"`
+	Precision int      `json:"precision"`
+}
+
+// Default is how numbers are written for anyone who hasn't picked something else
+var Default = Options{Notation: Suffix, Precision: DefaultPrecision}
+
+// Number writes n the way the options ask for. Zero, values under one and negative values are all
+// supported; values past the last suffix fall back to scientific notation.
+func (o Options) Number(n float64) string {
+	if o.Notation == "" {
+		o = Default
+	}
+	if o.Precision < 0 {
+		o.Precision = 0
+	}
+	if o.Precision > MaxPrecision {
+		o.Precision = MaxPrecision
+	}
+
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "∞"
+	case math.IsInf(n, -1):
+		return "-∞"
+	case n < 0:
+		return "-" + o.Number(-n)
+	}
+
+	switch o.Notation {
+	case Scientific:
+		return scientific(n, o.Precision)
+	case Magnitude:
+		if n == 0 {
+			return "0"
+		}
+		return fmt.Sprintf("10^%.*f", o.Precision, math.Log10(n))
+	default:
+		return suffix(n, o.Precision)
+	}
+}
+
+// Number writes n the default way
+func Number(n float64) string {
+	return Default.Number(n)
+}
+
+func suffix(n float64, precision int) string {
+	if n < 1000 {
+		// rounding can carry sub-thousand values up into the next suffix
+		if rounded := fmt.Sprintf("%.*f", precision, n); !strings.HasPrefix(rounded, "1000") {
+			return rounded
+		}
+	}
+
+	magnitude := int(math.Floor(math.Log10(n) / 3))
+	if magnitude < 1 {
+		magnitude = 1
+	}
+	mantissa := n / math.Pow(1000, float64(magnitude))
+	// 999.9999k shown to 3 places is 1.000m, not 1000.000k
+	if strings.HasPrefix(fmt.Sprintf("%.*f", precision, mantissa), "1000") {
+		magnitude++
+		mantissa /= 1000
+	}
+	if magnitude >= len(Suffixes) {
+		return scientific(n, precision)
+	}
+
+	return fmt.Sprintf("%.*f%s", precision, mantissa, Suffixes[magnitude])
+}
+
+func scientific(n float64, precision int) string {
+	// Go writes 1.5e+18 and 5e-01, the game and the parser are happy with 1.5e18 and 5e-1
+	written := strconv.FormatFloat(n, 'e', precision, 64)
+	i := strings.LastIndex(written, "e")
+	exponent, _ := strconv.Atoi(written[i+1:])
+	return fmt.Sprintf("%se%d", written[:i], exponent)
+}
+
+// Parse reads a number the way players write them: plain ("1500"), with a suffix ("1.5k", "2.25Q",
+// "3Td") or in scientific notation ("1.5e18"). Suffixes are case sensitive, since q and Q are a
+// quadrillion and a quintillion.
+func Parse(input string) (float64, error) {
+	trimmed := strings.ReplaceAll(strings.TrimSpace(input), ",", "")
+	if trimmed == "" {
+		return 0, errors.New("expected a number like 1.5Q or 1.5e18, but got nothing")
+	}
+
+	multiplier := float64(1)
+	// longest suffix first, so that Td isn't read as d
+	best := 0
+	for magnitude := 1; magnitude < len(Suffixes); magnitude++ {
+		if strings.HasSuffix(trimmed, Suffixes[magnitude]) && len(Suffixes[magnitude]) > len(Suffixes[best]) {
+			best = magnitude
+		}
+	}
+	if best > 0 {
+		trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, Suffixes[best]))
+		multiplier = math.Pow(1000, float64(best))
+	}
+
+	value, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errors.New(fmt.Sprintf("'%s' isn't a number I understand. Try something like 1.5Q or 1.5e18", input))
+	}
+	if value < 0 {
+		return 0, errors.New(fmt.Sprintf("'%s' can't be negative", input))
+	}
+
+	return value * multiplier, nil
+}
@@ -0,0 +1,93 @@
+package format
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		n       float64
+		written string
+	}{
+		{name: "zero", options: Default, n: 0, written: "0.000"},
+		{name: "under one", options: Default, n: 0.5, written: "0.500"},
+		{name: "sub-kilo", options: Default, n: 999, written: "999.000"},
+		{name: "kilo", options: Default, n: 1500, written: "1.500k"},
+		{name: "quintillion", options: Default, n: 1.5e18, written: "1.500Q"},
+		{name: "decillion", options: Default, n: 1e33, written: "1.000d"},
+		{name: "undecillion", options: Default, n: 2e36, written: "2.000U"},
+		{name: "tredecillion", options: Default, n: 1e42, written: "1.000Td"},
+		{name: "vigintillion", options: Default, n: 1e63, written: "1.000V"},
+		{name: "trigintillion", options: Default, n: 1e93, written: "1.000tT"},
+		{name: "past the last suffix", options: Default, n: 1e96, written: "1.000e96"},
+		{name: "negative", options: Default, n: -1500, written: "-1.500k"},
+		{name: "rounds up into the next suffix", options: Default, n: 999999.9999, written: "1.000m"},
+		{name: "rounds up out of sub-kilo", options: Default, n: 999.9999, written: "1.000k"},
+		{name: "zero value options", options: Options{}, n: 1.5e18, written: "1.500Q"},
+		{name: "precision", options: Options{Notation: Suffix, Precision: 1}, n: 1.25e18, written: "1.2Q"},
+		{name: "no decimal places", options: Options{Notation: Suffix, Precision: 0}, n: 1.5e18, written: "2Q"},
+		{name: "precision is capped", options: Options{Notation: Suffix, Precision: 20}, n: 1.5, written: "1.500000"},
+		{name: "scientific", options: Options{Notation: Scientific, Precision: 3}, n: 1.5e18, written: "1.500e18"},
+		{name: "scientific under one", options: Options{Notation: Scientific, Precision: 2}, n: 0.05, written: "5.00e-2"},
+		{name: "scientific zero", options: Options{Notation: Scientific, Precision: 1}, n: 0, written: "0.0e0"},
+		{name: "scientific negative", options: Options{Notation: Scientific, Precision: 1}, n: -2e40, written: "-2.0e40"},
+		{name: "magnitude", options: Options{Notation: Magnitude, Precision: 3}, n: 1.5e18, written: "10^18.176"},
+		{name: "magnitude rounded", options: Options{Notation: Magnitude, Precision: 0}, n: 1.5e18, written: "10^18"},
+		{name: "magnitude zero", options: Options{Notation: Magnitude, Precision: 3}, n: 0, written: "0"},
+		{name: "not a number", options: Default, n: math.NaN(), written: "NaN"},
+		{name: "infinity", options: Default, n: math.Inf(1), written: "∞"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.written, tt.options.Number(tt.n))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		value float64
+		err   bool
+	}{
+		{name: "plain", input: "1500", value: 1500},
+		{name: "commas", input: "1,500", value: 1500},
+		{name: "suffix", input: "1.5Q", value: 1.5e18},
+		{name: "lower case suffix is a different number", input: "1.5q", value: 1.5e15},
+		{name: "space before the suffix", input: "2 k", value: 2000},
+		{name: "two letter suffix", input: "3Td", value: 3e42},
+		{name: "last suffix", input: "1tT", value: 1e93},
+		{name: "scientific", input: "1.5e18", value: 1.5e18},
+		{name: "empty", input: " ", err: true},
+		{name: "unknown suffix", input: "1.5x", err: true},
+		{name: "negative", input: "-1Q", err: true},
+		{name: "infinity", input: "Inf", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := Parse(tt.input)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.InEpsilon(t, tt.value, value, 1e-9)
+		})
+	}
+
+	t.Run("round trips", func(t *testing.T) {
+		for _, n := range []float64{1.5e18, 2.25e36, 7e50, 1e90} {
+			value, err := Parse(Number(n))
+			require.NoError(t, err)
+			require.InEpsilon(t, n, value, 1e-9)
+		}
+	})
+}
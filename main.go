@@ -2,47 +2,139 @@ package main
 
 import (
 	"context"
+	"egg/api"
 	"egg/bot"
 	"egg/config"
 	"egg/datastore"
+	"egg/refresh"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	ctx := context.Background()
-	db, err := datastore.ConnectDatabase("sqlite-file", true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	configFile := config.ConfigFile()
+	// migrations only touch the datastore, so they don't need a bot token
+	migrating := len(os.Args) > 1 && os.Args[1] == "migrate"
+	load := config.Load
+	if migrating {
+		load = config.LoadDatabase
+	}
+	cfg, err := load(configFile)
 	if err != nil {
-		panic(err)
+		logrus.Fatal(err)
 	}
+	logrus.Infof("--> effective config: %s", cfg)
 
-	if err = db.AutoMigrate(datastore.User{}); err != nil {
+	db, err := datastore.Connect(datastore.Options{
+		URL:                    cfg.Database.URL,
+		SQLitePath:             cfg.Database.SQLitePath,
+		MaxOpenConns:           cfg.Database.MaxOpenConns,
+		MaxIdleConns:           cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:        cfg.Database.ConnMaxLifetime(),
+		SilenceTransactionLogs: !cfg.Database.LogTransactions,
+	})
+	if err != nil {
 		panic(err)
 	}
 
-	dStore := datastore.Database{DB: db}
+	if migrating {
+		if err = runMigrateCommand(db, os.Args[2:]); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
 
-	if err = config.LoadConfigFromFile("./config.json"); err != nil {
+	if err = datastore.Migrate(db); err != nil {
 		panic(err)
 	}
 
-	commands, session := bot.Start(ctx, dStore)
+	dStore := datastore.Database{DB: db}
+
+	client := api.NewClient(cfg.API)
+
+	b := bot.Start(ctx, cfg, dStore, client)
 	defer func() {
-		_ = session.Close()
+		_ = b.Session.Close()
+	}()
+
+	refresher := &refresh.Refresher{
+		Store:       dStore,
+		Client:      client,
+		Interval:    cfg.RefreshInterval(),
+		Concurrency: cfg.Concurrency(),
+		AfterRefresh: func(ctx context.Context) {
+			if err := api.PruneSnapshots(ctx, dStore, time.Now(), b.Config().SnapshotRetention()); err != nil {
+				logrus.WithError(err).Warn("--> failed to prune old snapshots")
+			}
+			if err := bot.UpdateLeaderboards(ctx, b.Session, dStore, b.Config().RefreshInterval()); err != nil {
+				logrus.WithError(err).Error("--> updating leaderboards failed")
+			}
+			b.SyncRankRoles(ctx)
+		},
+	}
+	refreshed := make(chan struct{})
+	go func() {
+		refresher.Run(ctx)
+		close(refreshed)
+	}()
+	alerted := make(chan struct{})
+	go func() {
+		b.RunMissionAlerts(ctx)
+		close(alerted)
+	}()
+	announced := make(chan struct{})
+	go func() {
+		b.RunEventAnnouncements(ctx)
+		close(announced)
 	}()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
-	<-stop
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 
-	logrus.Info("--> removing bot commands from server ...")
-	for _, command := range commands {
-		if err = session.ApplicationCommandDelete(session.State.User.ID, config.Config.GuildID, command.ID); err != nil {
-			panic(err)
+running:
+	for {
+		select {
+		case <-reload:
+			reloadConfig(ctx, configFile, b, refresher)
+		case <-stop:
+			break running
 		}
 	}
 
+	// cancel any in-flight Egg, Inc. API calls and wait for the background loops before tearing down the session
+	cancel()
+	<-refreshed
+	<-alerted
+	<-announced
+
+	logrus.Info("--> removing bot commands ...")
+	if err = b.RemoveCommands(); err != nil {
+		panic(err)
+	}
+
 	logrus.Info("--> gracefully shutting down ...")
 }
+
+// reloadConfig re-reads the config on SIGHUP and applies whatever can change without a restart.
+// A config that fails to load leaves the running one in place.
+func reloadConfig(ctx context.Context, configFile string, b *bot.Bot, refresher *refresh.Refresher) {
+	logrus.Infof("--> reloading config from %s ...", configFile)
+	next, err := config.Load(configFile)
+	if err != nil {
+		logrus.WithError(err).Error("--> keeping the running config")
+		return
+	}
+
+	b.Reload(ctx, next)
+	cfg := b.Config()
+	refresher.Reconfigure(cfg.RefreshInterval(), cfg.Concurrency())
+}
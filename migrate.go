@@ -0,0 +1,42 @@
+package main
+
+import (
+	"egg/datastore"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// runMigrateCommand handles `migrate <status|up|rollback>` from the command line
+func runMigrateCommand(db *gorm.DB, args []string) error {
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "status":
+		states, err := datastore.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			fmt.Println(state)
+		}
+	case "up":
+		if err := datastore.Migrate(db); err != nil {
+			return err
+		}
+		fmt.Println("all migrations applied")
+	case "rollback":
+		migration, err := datastore.RollbackLast(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %04d %s\n", migration.Version, migration.Name)
+	default:
+		return fmt.Errorf("unknown migrate command '%s', expected one of status, up or rollback", action)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package refresh
+
+import (
+	"context"
+	"egg/api"
+	"egg/datastore"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Refresher periodically re-fetches every registered user's backup and updates their stored progress
+type Refresher struct {
+	Store       datastore.Database
+	Client      *api.Client
+	Interval    time.Duration
+	Concurrency int
+
+	// AfterRefresh, if set, is called once every user has been refreshed, e.g. to redraw leaderboards
+	AfterRefresh func(ctx context.Context)
+
+	// mu guards Interval and Concurrency once Run has started
+	mu           sync.Mutex
+	reconfigured chan struct{}
+}
+
+// Run refreshes all users immediately and then once per Interval until ctx is cancelled
+func (r *Refresher) Run(ctx context.Context) {
+	r.mu.Lock()
+	r.reconfigured = make(chan struct{}, 1)
+	r.mu.Unlock()
+
+	logrus.Infof("--> refreshing registered users every %s ...", r.interval())
+
+	for {
+		switch err := r.RefreshAll(ctx); {
+		case err != nil:
+			logrus.WithError(err).Error("--> refreshing registered users failed")
+		case r.AfterRefresh != nil:
+			r.AfterRefresh(ctx)
+		}
+		refreshedAt := time.Now()
+
+	wait:
+		for {
+			timer := time.NewTimer(time.Until(refreshedAt.Add(r.interval())))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				logrus.Info("--> stopped refreshing registered users")
+				return
+			case <-r.reconfigured:
+				// wait out whatever is left of the new interval instead
+				timer.Stop()
+			case <-timer.C:
+				break wait
+			}
+		}
+	}
+}
+
+// Reconfigure changes how often and how many users at once are refreshed. A shorter interval
+// takes effect straight away rather than after the next refresh.
+func (r *Refresher) Reconfigure(interval time.Duration, concurrency int) {
+	r.mu.Lock()
+	r.Interval = interval
+	r.Concurrency = concurrency
+	reconfigured := r.reconfigured
+	r.mu.Unlock()
+
+	logrus.Infof("--> now refreshing registered users every %s, %d at a time", interval, concurrency)
+
+	if reconfigured != nil {
+		select {
+		case reconfigured <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *Refresher) interval() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Interval
+}
+
+// RefreshAll re-fetches the backup of every registered user, at most Concurrency at a time.
+// Failures for individual users are logged and don't stop the others from being refreshed.
+func (r *Refresher) RefreshAll(ctx context.Context) error {
+	tx, err := r.Store.Transaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	users, err := tx.GetUsers()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	concurrency := r.Concurrency
+	r.mu.Unlock()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed int
+	sem := make(chan struct{}, concurrency)
+	for _, user := range users {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(user datastore.User) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if refreshErr := r.refreshUser(ctx, user); refreshErr != nil {
+				logrus.WithError(refreshErr).WithField("egg_inc_id", user.EggIncID).Warn("--> failed to refresh user")
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(user)
+	}
+	wg.Wait()
+
+	logrus.Infof("--> refreshed %d of %d registered users", len(users)-failed, len(users))
+
+	return nil
+}
+
+func (r *Refresher) refreshUser(ctx context.Context, user datastore.User) error {
+	backup, err := r.Client.GetBackup(ctx, user.EggIncID)
+	if err != nil {
+		return err
+	}
+
+	if backup.EiUserId != user.EggIncID {
+		return errors.New(fmt.Sprintf("backup returned for '%s' instead of '%s'", backup.EiUserId, user.EggIncID))
+	}
+
+	_, err = api.AddUserToDatabase(ctx, r.Store, backup, "", user.DiscordID, user.DiscordName)
+	return err
+}
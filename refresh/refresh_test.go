@@ -0,0 +1,121 @@
+package refresh
+
+import (
+	"context"
+	"egg/api/apitest"
+	"egg/datastore"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshAll(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+	store := datastore.Database{DB: db}
+
+	ctx := context.Background()
+	tx, err := store.Transaction(ctx)
+	require.NoError(t, err)
+	for _, user := range []datastore.User{
+		{EggIncID: "EI1111", DiscordName: "krohmag", GameAccountName: "akroh", SoulEggs: 1},
+		{EggIncID: "EI2222", DiscordName: "someone", GameAccountName: "gone", SoulEggs: 1},
+	} {
+		_, err = tx.CreateOrUpdateUser(user)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	// only the first user still has a backup; the second should fail without stopping the first
+	server.SetBackup(apitest.Backup("EI1111", "akroh"))
+
+	refresher := Refresher{
+		Store:       store,
+		Client:      server.APIClient(),
+		Concurrency: 2,
+	}
+
+	t.Run("updates users with a backup", func(t *testing.T) {
+		require.NoError(t, refresher.RefreshAll(ctx))
+
+		tx, err := store.Transaction(ctx)
+		require.NoError(t, err)
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		refreshed, err := tx.GetUserByEggIncUserID("EI1111")
+		require.NoError(t, err)
+		require.Equal(t, 1.5e18, refreshed.SoulEggs)
+		require.Equal(t, int32(140), refreshed.SoulFood)
+		require.Equal(t, "krohmag", refreshed.DiscordName)
+
+		stale, err := tx.GetUserByEggIncUserID("EI2222")
+		require.NoError(t, err)
+		require.Equal(t, float64(1), stale.SoulEggs)
+	})
+
+	t.Run("tolerates API errors", func(t *testing.T) {
+		server.SetFault(apitest.PathFirstContact, apitest.Fault{StatusCode: http.StatusBadGateway})
+		defer server.ClearFaults()
+
+		require.NoError(t, refresher.RefreshAll(ctx))
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		require.Error(t, refresher.RefreshAll(cancelled))
+	})
+}
+
+func TestRunReconfigure(t *testing.T) {
+	db, err := datastore.ConnectDatabase("sqlite-in-memory", true)
+	require.NoError(t, err)
+	require.NoError(t, datastore.Migrate(db))
+
+	refreshed := make(chan struct{}, 1)
+	refresher := Refresher{
+		Store:       datastore.Database{DB: db},
+		Interval:    time.Hour,
+		Concurrency: 1,
+		AfterRefresh: func(ctx context.Context) {
+			select {
+			case refreshed <- struct{}{}:
+			default:
+			}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		refresher.Run(ctx)
+		close(done)
+	}()
+
+	// the first refresh happens straight away
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no refresh on start")
+	}
+
+	// and the next one follows the shorter interval rather than waiting out the hour
+	refresher.Reconfigure(10*time.Millisecond, 2)
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shorter interval didn't take effect")
+	}
+
+	cancel()
+	<-done
+}